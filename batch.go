@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AdamBuchen/kurtosis-take-home/scheduler"
+)
+
+// A job file found by the batch command, and where its ordering goes
+type batchJob struct {
+	inputPath  string
+	outputPath string
+}
+
+// How one job in a batch went. err is nil if its ordering was written.
+type batchResult struct {
+	batchJob
+	stepCount int
+	duration  time.Duration
+	err       error
+}
+
+// Expands each argument into job files. A directory is searched recursively for .yml and .yaml
+// files, skipping hidden directories; anything else is a file or a glob. Each job's output is the
+// input path with a .txt extension, or the same relative path under outputDir if one is given.
+func getBatchJobs(args []string, outputDir string) ([]batchJob, error) {
+
+	jobs := make([]batchJob, 0)
+	jobsByOutputPath := make(map[string]batchJob)
+
+	addJob := func(inputPath string, relativePath string) error {
+		outputPath := strings.TrimSuffix(inputPath, filepath.Ext(inputPath)) + ".txt"
+		if outputDir != "" {
+			outputPath = filepath.Join(outputDir, strings.TrimSuffix(relativePath, filepath.Ext(relativePath))+".txt")
+		}
+
+		if outputPath == inputPath {
+			return &usageError{message: fmt.Sprintf("%s would be overwritten by its own output", inputPath)}
+		}
+		if existing, isTaken := jobsByOutputPath[outputPath]; isTaken {
+			if existing.inputPath == inputPath {
+				return nil
+			}
+			return &usageError{message: fmt.Sprintf("%s and %s would both be written to %s", existing.inputPath, inputPath, outputPath)}
+		}
+
+		job := batchJob{inputPath: inputPath, outputPath: outputPath}
+		jobsByOutputPath[outputPath] = job
+		jobs = append(jobs, job)
+		return nil
+	}
+
+	for _, arg := range args {
+		if info, statErr := os.Stat(arg); statErr == nil && info.IsDir() {
+			walkErr := filepath.WalkDir(arg, func(path string, entry fs.DirEntry, walkErr error) error {
+				if walkErr != nil {
+					return walkErr
+				}
+				if entry.IsDir() {
+					if path != arg && strings.HasPrefix(entry.Name(), ".") {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if extension := filepath.Ext(path); extension != ".yml" && extension != ".yaml" {
+					return nil
+				}
+
+				relativePath, relErr := filepath.Rel(arg, path)
+				if relErr != nil {
+					return relErr
+				}
+				return addJob(path, relativePath)
+			})
+			if walkErr != nil {
+				return nil, walkErr
+			}
+			continue
+		}
+
+		matches, globErr := filepath.Glob(arg)
+		if globErr != nil {
+			return nil, &usageError{message: fmt.Sprintf("invalid pattern %s: %s", arg, globErr.Error())}
+		}
+		if len(matches) == 0 {
+			return nil, &usageError{message: fmt.Sprintf("no job files match %s", arg)}
+		}
+		for _, match := range matches {
+			if info, statErr := os.Stat(match); statErr != nil || info.IsDir() {
+				continue
+			}
+			if addErr := addJob(match, filepath.Base(match)); addErr != nil {
+				return nil, addErr
+			}
+		}
+	}
+
+	return jobs, nil
+}
+
+// Schedules every job with at most concurrency running at once, returning the results in the
+// order the jobs were given
+func processBatchJobs(jobs []batchJob, concurrency int) []batchResult {
+
+	results := make([]batchResult, len(jobs))
+	jobIndexes := make(chan int)
+
+	var waitGroup sync.WaitGroup
+	for worker := 0; worker < concurrency; worker++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			for jobIndex := range jobIndexes {
+				results[jobIndex] = processBatchJob(jobs[jobIndex])
+				logBatchResult(results[jobIndex])
+				if results[jobIndex].err != nil {
+					removeOutputAfterError(jobs[jobIndex].outputPath)
+				}
+			}
+		}()
+	}
+
+	for jobIndex := range jobs {
+		jobIndexes <- jobIndex
+	}
+	close(jobIndexes)
+	waitGroup.Wait()
+
+	return results
+}
+
+func processBatchJob(job batchJob) batchResult {
+
+	startTime := time.Now()
+	result := batchResult{batchJob: job}
+
+	yamlStr, fileReadErr := getStringFromPath(job.inputPath)
+	if fileReadErr != nil {
+		result.err = fmt.Errorf("could not open input path: %s", fileReadErr.Error())
+		result.duration = time.Since(startTime)
+		return result
+	}
+
+	outputLines, processingErr := scheduler.ProcessUserJob(yamlStr, getSchedulerOptions()...)
+	if processingErr != nil {
+		result.err = fmt.Errorf("could not process user job: %s", processingErr.Error())
+		result.duration = time.Since(startTime)
+		return result
+	}
+	result.stepCount = len(outputLines)
+
+	if mkdirErr := os.MkdirAll(filepath.Dir(job.outputPath), 0777); mkdirErr != nil {
+		result.err = fmt.Errorf("could not write out file: %s", mkdirErr.Error())
+	} else if saveErr := writeOrderingToFile(outputLines, job.outputPath); saveErr != nil {
+		result.err = fmt.Errorf("could not write out file: %s", saveErr.Error())
+	}
+
+	result.duration = time.Since(startTime)
+	return result
+}
+
+func logBatchResult(result batchResult) {
+	logger := slog.With("input", result.inputPath, "duration_ms", getMilliseconds(result.duration))
+	if result.err != nil {
+		logger.Warn("job failed", "error", result.err.Error())
+		return
+	}
+	logger.Info("job processed", "steps", result.stepCount, "output", result.outputPath)
+}
+
+// Renders a table of every job's status, step count, duration and output, sorted by input path,
+// followed by each failed job's full error and the totals
+func getBatchSummary(results []batchResult) string {
+
+	sortedResults := append([]batchResult{}, results...)
+	sort.Slice(sortedResults, func(i, j int) bool {
+		return sortedResults[i].inputPath < sortedResults[j].inputPath
+	})
+
+	var builder strings.Builder
+
+	pathWidth := len("job")
+	for _, result := range sortedResults {
+		if len(result.inputPath) > pathWidth {
+			pathWidth = len(result.inputPath)
+		}
+	}
+
+	builder.WriteString(fmt.Sprintf("  %-*s  %-6s  %5s  %8s  %s\n", pathWidth, "job", "status", "steps", "duration", "output"))
+	for _, result := range sortedResults {
+		status, output := "ok", result.outputPath
+		if result.err != nil {
+			status, output = "failed", "-"
+		}
+		builder.WriteString(fmt.Sprintf("  %-*s  %-6s  %5d  %8s  %s\n", pathWidth, result.inputPath, status, result.stepCount, result.duration.Round(time.Millisecond), output))
+	}
+
+	for _, result := range sortedResults {
+		if result.err != nil {
+			builder.WriteString(fmt.Sprintf("\n%s:\n", result.inputPath))
+			for _, line := range strings.Split(strings.TrimRight(result.err.Error(), "\n"), "\n") {
+				builder.WriteString("  " + line + "\n")
+			}
+		}
+	}
+
+	failedCount := countFailedBatchJobs(results)
+	builder.WriteString(fmt.Sprintf("\n%d job(s): %d succeeded, %d failed\n", len(sortedResults), len(sortedResults)-failedCount, failedCount))
+
+	return builder.String()
+}
+
+func countFailedBatchJobs(results []batchResult) int {
+
+	failedCount := 0
+	for _, result := range results {
+		if result.err != nil {
+			failedCount++
+		}
+	}
+
+	return failedCount
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindsBatchJobs(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "jobs", "a.yml"), validBatchJobYaml)
+	writeTestFile(t, filepath.Join(root, "jobs", "sub", "b.yaml"), validBatchJobYaml)
+	writeTestFile(t, filepath.Join(root, "jobs", ".hidden", "c.yml"), validBatchJobYaml)
+	writeTestFile(t, filepath.Join(root, "jobs", "notes.md"), "not a job")
+	writeTestFile(t, filepath.Join(root, "clash", "x.yml"), validBatchJobYaml)
+	writeTestFile(t, filepath.Join(root, "clash", "x.yaml"), validBatchJobYaml)
+	writeTestFile(t, filepath.Join(root, "other", "a.yml"), validBatchJobYaml)
+	writeTestFile(t, filepath.Join(root, "self", "job.txt"), validBatchJobYaml)
+
+	var tests = []struct {
+		args            []string
+		outputDir       string
+		expectedInputs  []string
+		expectedOutputs []string
+		isUsageError    bool
+	}{
+		// Directories are searched recursively, skipping hidden directories and other extensions
+		{[]string{"jobs"}, "", []string{"jobs/a.yml", "jobs/sub/b.yaml"}, []string{"jobs/a.txt", "jobs/sub/b.txt"}, false},
+		{[]string{"jobs"}, "out", []string{"jobs/a.yml", "jobs/sub/b.yaml"}, []string{"out/a.txt", "out/sub/b.txt"}, false},
+		// Globs keep just the file name under --output-dir
+		{[]string{"jobs/*.yml"}, "", []string{"jobs/a.yml"}, []string{"jobs/a.txt"}, false},
+		{[]string{"jobs/sub/*.yaml"}, "out", []string{"jobs/sub/b.yaml"}, []string{"out/b.txt"}, false},
+		// The same file named twice is one job
+		{[]string{"jobs", "jobs/a.yml"}, "", []string{"jobs/a.yml", "jobs/sub/b.yaml"}, []string{"jobs/a.txt", "jobs/sub/b.txt"}, false},
+		// Two jobs writing one output, a job overwritten by its own output, and a glob with no matches
+		{[]string{"clash"}, "", nil, nil, true},
+		{[]string{"jobs/*.yml", "other/*.yml"}, "out", nil, nil, true},
+		{[]string{"self/job.txt"}, "", nil, nil, true},
+		{[]string{"jobs/*.json"}, "", nil, nil, true},
+	}
+
+	for i, testCase := range tests {
+		args := make([]string, len(testCase.args))
+		for j, arg := range testCase.args {
+			args[j] = filepath.Join(root, arg)
+		}
+		outputDir := ""
+		if testCase.outputDir != "" {
+			outputDir = filepath.Join(root, testCase.outputDir)
+		}
+
+		jobs, jobsErr := getBatchJobs(args, outputDir)
+
+		if testCase.isUsageError {
+			var usageErr *usageError
+			if !errors.As(jobsErr, &usageErr) {
+				t.Errorf("test %d: expected a usage error, got %v", i, jobsErr)
+			}
+			continue
+		}
+		if jobsErr != nil {
+			t.Errorf("test %d: expected success, got error: %s", i, jobsErr.Error())
+			continue
+		}
+
+		inputs := make([]string, len(jobs))
+		outputs := make([]string, len(jobs))
+		for j, job := range jobs {
+			inputs[j] = getTestRelativePath(t, root, job.inputPath)
+			outputs[j] = getTestRelativePath(t, root, job.outputPath)
+		}
+		if !areStringSlicesEqual(inputs, testCase.expectedInputs) || !areStringSlicesEqual(outputs, testCase.expectedOutputs) {
+			t.Errorf("test %d: expected %v -> %v, got %v -> %v", i, testCase.expectedInputs, testCase.expectedOutputs, inputs, outputs)
+		}
+	}
+}
+
+func TestProcessesBatchJobs(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "good.yml"), validBatchJobYaml)
+	writeTestFile(t, filepath.Join(root, "bad.yml"), cyclicBatchJobYaml)
+
+	jobs, jobsErr := getBatchJobs([]string{filepath.Join(root, "*.yml")}, filepath.Join(root, "out"))
+	if jobsErr != nil {
+		t.Fatalf("received unexpected error: %s", jobsErr.Error())
+	}
+
+	results := processBatchJobs(jobs, 2)
+
+	// Results come back in the order the jobs were given, which for a glob is sorted
+	var tests = []struct {
+		inputPath string
+		stepCount int
+		isFailed  bool
+		ordering  string
+	}{
+		{"bad.yml", 0, true, ""},
+		{"good.yml", 2, false, "build\ndeploy\n"},
+	}
+
+	for i, testCase := range tests {
+		result := results[i]
+		if getTestRelativePath(t, root, result.inputPath) != testCase.inputPath {
+			t.Errorf("test %d: expected %s, got %s", i, testCase.inputPath, result.inputPath)
+			continue
+		}
+		if (result.err != nil) != testCase.isFailed || result.stepCount != testCase.stepCount {
+			t.Errorf("test %d: expected failed %v with %d steps, got %v with %d", i, testCase.isFailed, testCase.stepCount, result.err, result.stepCount)
+		}
+
+		contents, readErr := os.ReadFile(result.outputPath)
+		if testCase.isFailed {
+			if readErr == nil {
+				t.Errorf("test %d: a failed job should not write its output", i)
+			}
+			continue
+		}
+		if string(contents) != testCase.ordering {
+			t.Errorf("test %d: expected ordering %q, got %q", i, testCase.ordering, string(contents))
+		}
+	}
+
+	// One failed job is enough for the command to fail
+	if failedCount := countFailedBatchJobs(results); failedCount != 1 {
+		t.Errorf("expected 1 failed job, got %d", failedCount)
+	}
+	if summary := getBatchSummary(results); !strings.Contains(summary, "2 job(s): 1 succeeded, 1 failed") {
+		t.Errorf("unexpected summary:\n%s", summary)
+	}
+}
+
+func writeTestFile(t *testing.T, path string, contents string) {
+	if mkdirErr := os.MkdirAll(filepath.Dir(path), 0777); mkdirErr != nil {
+		t.Fatalf("could not create %s: %s", filepath.Dir(path), mkdirErr.Error())
+	}
+	if writeErr := os.WriteFile(path, []byte(contents), 0644); writeErr != nil {
+		t.Fatalf("could not write %s: %s", path, writeErr.Error())
+	}
+}
+
+func getTestRelativePath(t *testing.T, root string, path string) string {
+	relativePath, relErr := filepath.Rel(root, path)
+	if relErr != nil {
+		t.Fatalf("received unexpected error: %s", relErr.Error())
+	}
+	return filepath.ToSlash(relativePath)
+}
+
+func areStringSlicesEqual(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+const validBatchJobYaml string = `
+- step: "build"
+  dependencies: []
+  precedence: 100
+- step: "deploy"
+  dependencies: ["build"]
+  precedence: 50
+`
+
+const cyclicBatchJobYaml string = `
+- step: "build"
+  dependencies: ["deploy"]
+  precedence: 100
+- step: "deploy"
+  dependencies: ["build"]
+  precedence: 50
+`
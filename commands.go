@@ -0,0 +1,702 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/AdamBuchen/kurtosis-take-home/coordinator"
+	"github.com/AdamBuchen/kurtosis-take-home/scheduler"
+	"github.com/AdamBuchen/kurtosis-take-home/server"
+)
+
+// Subcommands, keyed by the first positional argument. Each receives the remaining arguments.
+// Anything not listed here falls through to the default <input> <output> scheduling mode.
+var commands = map[string]func(args []string){
+	"verify":     runVerifyCommand,
+	"explain":    runExplainCommand,
+	"diff":       runDiffCommand,
+	"lint":       runLintCommand,
+	"fmt":        runFmtCommand,
+	"fix":        runFixCommand,
+	"exec":       runExecCommand,
+	"serve":      runServeCommand,
+	"coordinate": runCoordinateCommand,
+	"work":       runWorkCommand,
+	"batch":      runBatchCommand,
+	"stats":      runStatsCommand,
+	"deps":       runDepsCommand,
+	"rdeps":      runRdepsCommand,
+	"path":       runPathCommand,
+}
+
+// A flag that can be passed more than once, collecting each value
+type stringListFlag []string
+
+func (list *stringListFlag) String() string {
+	return strings.Join(*list, ",")
+}
+
+func (list *stringListFlag) Set(value string) error {
+	*list = append(*list, value)
+	return nil
+}
+
+// Parses a "rule=severity" lint override as passed on the command line
+func parseLintRuleOverride(override string) (string, string, error) {
+
+	rule, severity, found := strings.Cut(override, "=")
+	if !found {
+		return "", "", fmt.Errorf("rule override must be in the form rule=severity: %s", override)
+	}
+
+	return strings.TrimSpace(rule), strings.TrimSpace(severity), nil
+}
+
+// verify <job yml> <ordering txt>
+// Checks a hand-edited ordering against the job and reports each violation with its position
+func runVerifyCommand(args []string) {
+	if len(args) != 2 {
+		handleUsageError("verify requires a job path and an ordering path")
+	}
+
+	yamlStr, fileReadErr := getStringFromPath(args[0])
+	if fileReadErr != nil {
+		handleFatalError("could not open input path", fileReadErr)
+	}
+
+	ordering, orderingReadErr := getStepIdsFromPath(args[1])
+	if orderingReadErr != nil {
+		handleFatalError("could not open ordering path", orderingReadErr)
+	}
+
+	violations, verifyErr := scheduler.VerifyUserJobOrdering(yamlStr, ordering, getSchedulerOptions()...)
+	if verifyErr != nil {
+		handleFatalError("could not process user job", verifyErr)
+	}
+
+	if len(violations) > 0 {
+		for _, violation := range violations {
+			fmt.Println(violation.String())
+		}
+		handleFailure(fmt.Sprintf("ordering has %d violation(s)", len(violations)), getViolationStepIds(violations))
+	}
+
+	fmt.Println("ordering is valid")
+}
+
+// Lists the step behind each violation, skipping those (like a missing step) without one
+func getViolationStepIds(violations []scheduler.OrderingViolation) []string {
+
+	stepIds := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		if violation.StepId != "" {
+			stepIds = append(stepIds, violation.StepId)
+		}
+	}
+
+	return stepIds
+}
+
+// explain <job yml> <step id>
+// Replays the scheduler and reports why the step landed at its position
+func runExplainCommand(args []string) {
+	if len(args) != 2 {
+		handleUsageError("explain requires a job path and a step ID")
+	}
+
+	yamlStr, fileReadErr := getStringFromPath(args[0])
+	if fileReadErr != nil {
+		handleFatalError("could not open input path", fileReadErr)
+	}
+
+	explanation, explainErr := scheduler.ExplainUserJobStep(yamlStr, args[1], getSchedulerOptions()...)
+	if explainErr != nil {
+		handleFatalError("could not explain step", explainErr)
+	}
+
+	fmt.Print(explanation.String())
+}
+
+// diff <old job yml> <new job yml>
+// Reports the semantic changes between two versions of a job and how they moved the ordering
+func runDiffCommand(args []string) {
+	if len(args) != 2 {
+		handleUsageError("diff requires an old job path and a new job path")
+	}
+
+	oldYamlStr, oldReadErr := getStringFromPath(args[0])
+	if oldReadErr != nil {
+		handleFatalError("could not open old input path", oldReadErr)
+	}
+
+	newYamlStr, newReadErr := getStringFromPath(args[1])
+	if newReadErr != nil {
+		handleFatalError("could not open new input path", newReadErr)
+	}
+
+	jobDiff, diffErr := scheduler.DiffUserJobs(oldYamlStr, newYamlStr, getSchedulerOptions()...)
+	if diffErr != nil {
+		handleFatalError("could not diff user jobs", diffErr)
+	}
+
+	fmt.Print(jobDiff.String())
+}
+
+// lint [--config lint.yml] [--rule rule=severity ...] <job yml>
+// Reports hygiene problems that validation lets through. Fails only on error-severity findings.
+func runLintCommand(args []string) {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	configPath := flags.String("config", "", "path to a YAML lint config keyed by rule name")
+	var ruleOverrides stringListFlag
+	flags.Var(&ruleOverrides, "rule", "override a rule's severity as rule=severity, may be repeated (rules: "+
+		strings.Join(scheduler.LintRuleNames(), ", ")+")")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		handleUsageError("lint requires a job path")
+	}
+
+	config := scheduler.DefaultLintConfig()
+	if *configPath != "" {
+		configStr, configReadErr := getStringFromPath(*configPath)
+		if configReadErr != nil {
+			handleFatalError("could not open config path", configReadErr)
+		}
+
+		var configErr error
+		config, configErr = scheduler.ParseLintConfig(configStr)
+		if configErr != nil {
+			handleFatalError("could not parse lint config", configErr)
+		}
+	}
+
+	for _, override := range ruleOverrides {
+		rule, severity, overrideErr := parseLintRuleOverride(override)
+		if overrideErr == nil {
+			overrideErr = config.SetSeverity(rule, severity)
+		}
+		if overrideErr != nil {
+			handleUsageError(overrideErr.Error())
+		}
+	}
+
+	yamlStr, fileReadErr := getStringFromPath(flags.Arg(0))
+	if fileReadErr != nil {
+		handleFatalError("could not open input path", fileReadErr)
+	}
+
+	findings, lintErr := scheduler.LintUserJob(yamlStr, config, getSchedulerOptions()...)
+	for _, finding := range findings {
+		fmt.Println(finding.String())
+	}
+	if lintErr != nil {
+		handleFatalError("could not process user job", lintErr)
+	}
+
+	errorStepIds := make([]string, 0)
+	for _, finding := range findings {
+		if finding.Severity == scheduler.SeverityError {
+			errorStepIds = append(errorStepIds, finding.StepId)
+		}
+	}
+	if len(errorStepIds) > 0 {
+		handleFailure(fmt.Sprintf("lint found %d error(s)", len(errorStepIds)), errorStepIds)
+	}
+
+	fmt.Printf("lint finished with %d finding(s)\n", len(findings))
+}
+
+// fmt [--check] [--order none|alphabetical|topological] <job yml>...
+// Rewrites each job in canonical form, or with --check just reports the ones that aren't
+func runFmtCommand(args []string) {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	check := flags.Bool("check", false, "report unformatted files and exit non-zero instead of rewriting them")
+	stepOrder := flags.String("order", scheduler.FormatOrderNone, "step order: none, alphabetical or topological")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		handleUsageError("fmt requires at least one job path")
+	}
+
+	switch *stepOrder {
+	case scheduler.FormatOrderNone, scheduler.FormatOrderAlphabetical, scheduler.FormatOrderTopological:
+	default:
+		handleUsageError("unknown step order: " + *stepOrder)
+	}
+
+	unformattedCount := 0
+	for _, inputPath := range flags.Args() {
+		yamlStr, fileReadErr := getStringFromPath(inputPath)
+		if fileReadErr != nil {
+			handleFatalError("could not open input path", fileReadErr)
+		}
+
+		formatted, formatErr := scheduler.FormatUserJob(yamlStr, *stepOrder, getSchedulerOptions()...)
+		if formatErr != nil {
+			handleFatalError("could not format "+inputPath, formatErr)
+		}
+
+		if formatted == yamlStr {
+			continue
+		}
+		unformattedCount++
+
+		if *check {
+			fmt.Println("not formatted: " + inputPath)
+			continue
+		}
+
+		if saveErr := writeStringToFile(formatted, inputPath); saveErr != nil {
+			handleFatalError("could not write out file", saveErr)
+		}
+		fmt.Println("formatted: " + inputPath)
+	}
+
+	if *check && unformattedCount > 0 {
+		handleFailure(fmt.Sprintf("%d file(s) not formatted", unformattedCount), nil)
+	}
+}
+
+// fix [--output fixed.yml] <job yml>
+// Applies safe fixes for common validation failures, writing the job back in place by default
+func runFixCommand(args []string) {
+	flags := flag.NewFlagSet("fix", flag.ExitOnError)
+	outputPath := flags.String("output", "", "write the fixed job here instead of back to the input path")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		handleUsageError("fix requires a job path")
+	}
+
+	inputPath := flags.Arg(0)
+	if *outputPath == "" {
+		*outputPath = inputPath
+	}
+
+	yamlStr, fileReadErr := getStringFromPath(inputPath)
+	if fileReadErr != nil {
+		handleFatalError("could not open input path", fileReadErr)
+	}
+
+	fixed, fixes, processingErr := scheduler.FixUserJob(yamlStr, getSchedulerOptions()...)
+	for _, fix := range fixes {
+		fmt.Println("fixed " + fix)
+	}
+
+	if len(fixes) > 0 || *outputPath != inputPath {
+		if saveErr := writeStringToFile(fixed, *outputPath); saveErr != nil {
+			handleFatalError("could not write out file", saveErr)
+		}
+	}
+
+	if processingErr != nil {
+		handleFatalError("could not process user job", processingErr)
+	}
+
+	fmt.Printf("applied %d fix(es), job is valid\n", len(fixes))
+}
+
+// exec [--keep-going] [--cache-dir dir] [--no-cache] <job yml>
+// Runs each step's command in scheduled order, streaming prefixed output. Stops at the first
+// failure unless --keep-going is given, in which case only the failed steps' dependents are skipped.
+func runExecCommand(args []string) {
+	flags := flag.NewFlagSet("exec", flag.ExitOnError)
+	var keepGoing bool
+	flags.BoolVar(&keepGoing, "keep-going", false, "keep running steps that don't depend on a failed step")
+	flags.BoolVar(&keepGoing, "k", false, "shorthand for --keep-going")
+	cacheDir := flags.String("cache-dir", ".stepcache", "directory for cached outputs of steps that declare inputs or outputs")
+	noCache := flags.Bool("no-cache", false, "run every step, neither reading nor writing the cache")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		handleUsageError("exec requires a job path")
+	}
+
+	opts := getSchedulerOptions()
+	if keepGoing {
+		opts = append(opts, scheduler.WithKeepGoing())
+	}
+	if !*noCache && *cacheDir != "" {
+		opts = append(opts, scheduler.WithCacheDir(*cacheDir))
+	}
+
+	yamlStr, fileReadErr := getStringFromPath(flags.Arg(0))
+	if fileReadErr != nil {
+		handleFatalError("could not open input path", fileReadErr)
+	}
+
+	// An interrupt kills the running step and stops the job rather than abandoning the child
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	report, execErr := scheduler.ExecuteUserJob(ctx, yamlStr, os.Stdout, os.Stderr, opts...)
+	if execErr != nil {
+		handleFatalError("could not process user job", execErr)
+	}
+
+	fmt.Print("\n" + report.String())
+
+	if failedCount := len(report.Failed()); failedCount > 0 {
+		handleFailure(fmt.Sprintf("%d step(s) failed", failedCount), report.Failed())
+	}
+	if !report.Succeeded() {
+		handleFailure("job did not complete", report.NotRun)
+	}
+}
+
+// serve [--addr host:port] [--shutdown-timeout duration]
+// Serves POST /schedule, POST /validate and GET /healthz until interrupted, then lets in-flight
+// requests finish before exiting
+func runServeCommand(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", ":8080", "address to listen on")
+	shutdownTimeout := flags.Duration("shutdown-timeout", 10*time.Second, "how long to wait for in-flight requests when shutting down")
+	flags.Parse(args)
+
+	if flags.NArg() != 0 {
+		handleUsageError("serve takes no positional arguments")
+	}
+
+	// Bind before saying we're listening, so a script waiting for that line can connect straight away
+	listener, listenErr := net.Listen("tcp", *addr)
+	if listenErr != nil {
+		handleFatalError("could not listen", listenErr)
+	}
+
+	httpServer := &http.Server{
+		Handler:           server.NewHandler(resourceLimits),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.Serve(listener)
+	}()
+	fmt.Println("listening on " + listener.Addr().String())
+
+	select {
+	case listenErr := <-serveErr:
+		handleFatalError("could not serve", listenErr)
+	case <-ctx.Done():
+	}
+
+	fmt.Println("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+
+	if shutdownErr := httpServer.Shutdown(shutdownCtx); shutdownErr != nil && !errors.Is(shutdownErr, http.ErrServerClosed) {
+		handleFatalError("could not shut down cleanly", shutdownErr)
+	}
+}
+
+// coordinate [--listen host:port|unix:path] [--lease-ttl duration] [--drain-timeout duration] [--keep-going] <job yml>
+// Hands the job's steps out to workers started with the work command, then prints the report
+// and exits once every step has finished or the job has stopped on a failure
+func runCoordinateCommand(args []string) {
+	flags := flag.NewFlagSet("coordinate", flag.ExitOnError)
+	listenAddr := flags.String("listen", "127.0.0.1:8090", "host:port or unix:<path> to serve workers on")
+	leaseTtl := flags.Duration("lease-ttl", coordinator.DefaultLeaseTtl, "how long a worker keeps a step without a heartbeat")
+	drainTimeout := flags.Duration("drain-timeout", 30*time.Second, "how long to wait after the job for workers to hear it's done")
+	var keepGoing bool
+	flags.BoolVar(&keepGoing, "keep-going", false, "keep handing out steps that don't depend on a failed step")
+	flags.BoolVar(&keepGoing, "k", false, "shorthand for --keep-going")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		handleUsageError("coordinate requires a job path")
+	}
+	if *leaseTtl <= 0 {
+		handleUsageError("lease-ttl must be greater than zero")
+	}
+	if *drainTimeout < 0 {
+		handleUsageError("drain-timeout must not be negative")
+	}
+
+	yamlStr, fileReadErr := getStringFromPath(flags.Arg(0))
+	if fileReadErr != nil {
+		handleFatalError("could not open input path", fileReadErr)
+	}
+
+	inputJob, parseErr := scheduler.Parse(yamlStr, getSchedulerOptions()...)
+	if parseErr != nil {
+		handleFatalError("could not process user job", parseErr)
+	}
+	job, validateErr := scheduler.Validate(inputJob)
+	if validateErr != nil {
+		handleFatalError("could not process user job", validateErr)
+	}
+
+	opts := []coordinator.Option{coordinator.WithLeaseTtl(*leaseTtl)}
+	if keepGoing {
+		opts = append(opts, coordinator.WithKeepGoing())
+	}
+	schedulingCtx, cancel := scheduler.NewProcessingContext(resourceLimits)
+	jobCoordinator, coordinatorErr := coordinator.New(schedulingCtx, job, opts...)
+	cancel()
+	if coordinatorErr != nil {
+		handleFatalError("could not process user job", coordinatorErr)
+	}
+
+	listener, listenErr := coordinator.Listen(*listenAddr)
+	if listenErr != nil {
+		handleFatalError("could not listen", listenErr)
+	}
+
+	httpServer := &http.Server{
+		Handler:           coordinator.NewHandler(jobCoordinator),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.Serve(listener)
+	}()
+	fmt.Printf("coordinating %d step(s) on %s\n", len(job.Steps), *listenAddr)
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+waitForJob:
+	for !jobCoordinator.Finished() {
+		select {
+		case serveErr := <-serveErr:
+			handleFatalError("could not serve", serveErr)
+		case <-ctx.Done():
+			break waitForJob
+		case <-ticker.C:
+		}
+	}
+
+	// Workers learn the job is done by asking for more work, however long their poll interval is,
+	// so keep serving until every worker that has asked has heard. One that never asks again has
+	// probably gone away, so give up on it after --drain-timeout.
+	drainDeadline := time.After(*drainTimeout)
+waitForWorkers:
+	for ctx.Err() == nil && len(jobCoordinator.PendingWorkers()) > 0 {
+		select {
+		case <-drainDeadline:
+			pendingWorkers := jobCoordinator.PendingWorkers()
+			fmt.Fprintf(os.Stderr, "gave up waiting for %d worker(s) to hear the job is done: %s\n", len(pendingWorkers), strings.Join(pendingWorkers, ", "))
+			break waitForWorkers
+		case <-ctx.Done():
+		case <-ticker.C:
+		}
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	httpServer.Shutdown(shutdownCtx)
+
+	report := jobCoordinator.Report()
+	fmt.Print("\n" + report.String())
+
+	if failedCount := len(report.Failed()); failedCount > 0 {
+		handleFailure(fmt.Sprintf("%d step(s) failed", failedCount), report.Failed())
+	}
+	if !report.Succeeded() {
+		handleFailure("job did not complete", report.NotRun)
+	}
+}
+
+// work [--coordinator host:port|unix:path] [--worker-id id] [--poll-interval duration]
+// Leases steps from a coordinator and runs them one at a time until the job is done
+func runWorkCommand(args []string) {
+	flags := flag.NewFlagSet("work", flag.ExitOnError)
+	coordinatorAddr := flags.String("coordinator", "127.0.0.1:8090", "host:port or unix:<path> of the coordinator")
+	workerId := flags.String("worker-id", "", "name reported to the coordinator (default host:pid)")
+	pollInterval := flags.Duration("poll-interval", coordinator.DefaultPollInterval, "how long to wait before asking again when nothing is ready")
+	flags.Parse(args)
+
+	if flags.NArg() != 0 {
+		handleUsageError("work takes no positional arguments")
+	}
+
+	if *workerId == "" {
+		hostname, _ := os.Hostname()
+		*workerId = fmt.Sprintf("%s:%d", hostname, os.Getpid())
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	execute := func(ctx context.Context, step *scheduler.Step) scheduler.StepResult {
+		result := scheduler.ExecuteStep(ctx, step, os.Stdout, os.Stderr)
+		fmt.Fprintln(os.Stderr, result.String())
+		return result
+	}
+
+	client := coordinator.NewClient(*coordinatorAddr)
+	if workErr := coordinator.RunWorker(ctx, client, *workerId, execute, *pollInterval); workErr != nil {
+		handleFatalError("worker stopped", workErr)
+	}
+}
+
+// batch [--jobs n] [--output-dir dir] <dir|glob>...
+// Schedules every job file found, several at a time, and prints a summary table
+func runBatchCommand(args []string) {
+	flags := flag.NewFlagSet("batch", flag.ExitOnError)
+	concurrency := flags.Int("jobs", runtime.NumCPU(), "how many jobs to process at once")
+	outputDir := flags.String("output-dir", "", "write orderings under this directory instead of next to each job")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		handleUsageError("batch requires at least one directory or glob")
+	}
+	if *concurrency < 1 {
+		handleUsageError("jobs must be at least 1")
+	}
+
+	jobs, jobsErr := getBatchJobs(flags.Args(), *outputDir)
+	if jobsErr != nil {
+		handleFatalError("could not find jobs", jobsErr)
+	}
+	if len(jobs) == 0 {
+		handleUsageError("no job files found")
+	}
+
+	results := processBatchJobs(jobs, *concurrency)
+	fmt.Print(getBatchSummary(results))
+
+	if failedCount := countFailedBatchJobs(results); failedCount > 0 {
+		handleFailure(fmt.Sprintf("%d of %d job(s) failed", failedCount, len(results)), nil)
+	}
+}
+
+// stats [--format text|json] [--top n] <job yml>
+// Reports the size and shape of the job's dependency graph, for capacity planning
+func runStatsCommand(args []string) {
+	flags := flag.NewFlagSet("stats", flag.ExitOnError)
+	format := flags.String("format", "text", "output format: text or json")
+	topCount := flags.Int("top", 5, "how many steps to list by blast radius, 0 for all")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		handleUsageError("stats requires a job path")
+	}
+	checkOutputFormat(*format)
+	if *topCount < 0 {
+		handleUsageError("top must not be negative")
+	}
+
+	yamlStr, fileReadErr := getStringFromPath(flags.Arg(0))
+	if fileReadErr != nil {
+		handleFatalError("could not open input path", fileReadErr)
+	}
+
+	stats, statsErr := scheduler.StatsUserJob(yamlStr, *topCount, getSchedulerOptions()...)
+	if statsErr != nil {
+		handleFatalError("could not measure user job", statsErr)
+	}
+
+	printResult(stats, *format)
+}
+
+// deps [--transitive] [--format text|json] <job yml> <step id>
+// Lists the steps the given step depends on
+func runDepsCommand(args []string) {
+	runRelationsCommand("deps", scheduler.FindUserJobDependencies, args)
+}
+
+// rdeps [--transitive] [--format text|json] <job yml> <step id>
+// Lists the steps that depend on the given step
+func runRdepsCommand(args []string) {
+	runRelationsCommand("rdeps", scheduler.FindUserJobDependents, args)
+}
+
+func runRelationsCommand(
+	name string,
+	findRelations func(string, string, bool, ...scheduler.Option) (*scheduler.StepRelations, error),
+	args []string,
+) {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	var transitive bool
+	flags.BoolVar(&transitive, "transitive", false, "include indirect relations, not just direct ones")
+	flags.BoolVar(&transitive, "t", false, "shorthand for --transitive")
+	format := flags.String("format", "text", "output format: text or json")
+	flags.Parse(args)
+
+	if flags.NArg() != 2 {
+		handleUsageError(name + " requires a job path and a step ID")
+	}
+	checkOutputFormat(*format)
+
+	yamlStr, fileReadErr := getStringFromPath(flags.Arg(0))
+	if fileReadErr != nil {
+		handleFatalError("could not open input path", fileReadErr)
+	}
+
+	relations, findErr := findRelations(yamlStr, flags.Arg(1), transitive, getSchedulerOptions()...)
+	if findErr != nil {
+		handleFatalError("could not query user job", findErr)
+	}
+
+	printResult(relations, *format)
+}
+
+// path [--shortest] [--max n] [--format text|json] <job yml> <step id> <step id>
+// Lists the dependency chains between two steps, in whichever direction they depend on each other
+func runPathCommand(args []string) {
+	flags := flag.NewFlagSet("path", flag.ExitOnError)
+	shortest := flags.Bool("shortest", false, "only list the shortest paths")
+	maxPaths := flags.Int("max", 100, "stop after this many paths, 0 for no limit")
+	format := flags.String("format", "text", "output format: text or json")
+	flags.Parse(args)
+
+	if flags.NArg() != 3 {
+		handleUsageError("path requires a job path and two step IDs")
+	}
+	checkOutputFormat(*format)
+	if *maxPaths < 0 {
+		handleUsageError("max must not be negative")
+	}
+
+	yamlStr, fileReadErr := getStringFromPath(flags.Arg(0))
+	if fileReadErr != nil {
+		handleFatalError("could not open input path", fileReadErr)
+	}
+
+	dependencyPaths, findErr := scheduler.FindUserJobPaths(yamlStr, flags.Arg(1), flags.Arg(2), *shortest, *maxPaths, getSchedulerOptions()...)
+	if findErr != nil {
+		handleFatalError("could not query user job", findErr)
+	}
+
+	printResult(dependencyPaths, *format)
+}
+
+// Exits unless format is one printResult knows
+func checkOutputFormat(format string) {
+	if format != "text" && format != "json" {
+		handleUsageError("unknown output format: " + format)
+	}
+}
+
+// Prints a command's result as its String() or as indented JSON
+func printResult(result fmt.Stringer, format string) {
+	if format == "text" {
+		fmt.Print(result.String())
+		return
+	}
+
+	encoded, marshalErr := json.MarshalIndent(result, "", "  ")
+	if marshalErr != nil {
+		handleFatalError("could not encode result", marshalErr)
+	}
+	fmt.Println(string(encoded))
+}
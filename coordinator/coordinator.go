@@ -0,0 +1,324 @@
+// Package coordinator spreads a job's steps across worker processes. A Coordinator holds the
+// job graph and leases ready steps to workers in precedence/ID order. Workers heartbeat to keep
+// their lease, and report the step's result when it finishes. A lease that isn't renewed in time
+// expires, and its step goes back to the ready queue for another worker.
+//
+// NewHandler serves a Coordinator over HTTP, on TCP or a Unix socket (see Listen), and Client
+// and RunWorker are the worker's side of that API.
+package coordinator
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/AdamBuchen/kurtosis-take-home/scheduler"
+)
+
+var (
+	// Nothing is ready right now, but more may be once running steps finish. Ask again later.
+	ErrNoWork = errors.New("no step is ready")
+
+	// Nothing more will be handed out, either because every step has finished or because a step
+	// failed and the coordinator isn't keeping going
+	ErrJobDone = errors.New("job is done")
+
+	// The lease expired, or was never granted. The step may already have gone to another worker,
+	// so the holder should stop working on it and not report a result.
+	ErrLeaseLost = errors.New("lease not found or expired")
+)
+
+// How long a lease lasts without a heartbeat, unless WithLeaseTtl says otherwise
+const DefaultLeaseTtl = 30 * time.Second
+
+// A step handed to a worker, with everything needed to run it
+type Lease struct {
+	LeaseId    string             `json:"lease"`
+	StepId     string             `json:"step"`
+	Run        *scheduler.StepRun `json:"run,omitempty"`
+	Retries    int                `json:"retries,omitempty"`
+	BackoffMs  int64              `json:"retry_backoff_ms,omitempty"`
+	TimeoutMs  int64              `json:"timeout_ms,omitempty"`
+	TtlMs      int64              `json:"ttl_ms"` // How long the lease lasts between heartbeats
+	ExpiresAt  time.Time          `json:"expires_at"`
+	WorkerId   string             `json:"worker"`
+	Precedence int64              `json:"precedence"`
+}
+
+// Rebuilds the step the lease is for, ready to run with scheduler.ExecuteStep
+func (lease *Lease) GetStep() *scheduler.Step {
+	return &scheduler.Step{
+		StepName:     lease.StepId,
+		StepId:       lease.StepId,
+		Precedence:   lease.Precedence,
+		Run:          lease.Run,
+		Retries:      lease.Retries,
+		RetryBackoff: time.Duration(lease.BackoffMs) * time.Millisecond,
+		Timeout:      time.Duration(lease.TimeoutMs) * time.Millisecond,
+	}
+}
+
+// Where a Coordinator's job stands
+type Status struct {
+	States   map[string]scheduler.StepState `json:"states"`
+	Leases   int                            `json:"leases"`   // Leases currently held
+	Requeues int                            `json:"requeues"` // Leases that expired and went back to the queue
+	Finished bool                           `json:"finished"`
+}
+
+// Configures a Coordinator
+type Option func(*Coordinator)
+
+// Sets how long a lease lasts between heartbeats
+func WithLeaseTtl(ttl time.Duration) Option {
+	return func(coordinator *Coordinator) {
+		coordinator.leaseTtl = ttl
+	}
+}
+
+// Keeps handing out steps that don't depend on a failed one, instead of stopping at the first
+// failure, like make -k
+func WithKeepGoing() Option {
+	return func(coordinator *Coordinator) {
+		coordinator.keepGoing = true
+	}
+}
+
+// Replaces the clock, so tests can expire leases without waiting
+func WithClock(now func() time.Time) Option {
+	return func(coordinator *Coordinator) {
+		coordinator.now = now
+	}
+}
+
+// Hands out a job's steps to workers. A Coordinator is safe for concurrent use.
+type Coordinator struct {
+	mutex     sync.Mutex
+	job       *scheduler.Job
+	steps     *scheduler.Scheduler
+	leaseTtl  time.Duration
+	keepGoing bool
+	now       func() time.Time
+
+	leasesById  map[string]*Lease
+	workersDone map[string]bool // Every worker that has asked for work, and whether it's been told the job is done
+	report      scheduler.ExecutionReport
+	requeues    int
+	stopped     bool // A step failed and we aren't keeping going
+}
+
+// Builds a Coordinator for the job with every step pending. Returns a *scheduler.CycleError if
+// the job can't be fully scheduled, or the context's error if ctx is done first.
+func New(ctx context.Context, job *scheduler.Job, opts ...Option) (*Coordinator, error) {
+
+	steps, schedulerErr := scheduler.NewScheduler(ctx, job)
+	if schedulerErr != nil {
+		return nil, schedulerErr
+	}
+
+	coordinator := &Coordinator{
+		job:         job,
+		steps:       steps,
+		leaseTtl:    DefaultLeaseTtl,
+		now:         time.Now,
+		leasesById:  make(map[string]*Lease),
+		workersDone: make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(coordinator)
+	}
+
+	return coordinator, nil
+}
+
+// Leases the first ready step in precedence/ID order to the worker. Returns ErrNoWork if nothing
+// is ready yet, and ErrJobDone if nothing more will be handed out.
+func (coordinator *Coordinator) Lease(workerId string) (*Lease, error) {
+	coordinator.mutex.Lock()
+	defer coordinator.mutex.Unlock()
+
+	coordinator.expireLeases()
+
+	if coordinator.stopped || coordinator.steps.Done() {
+		coordinator.workersDone[workerId] = true
+		return nil, ErrJobDone
+	}
+	coordinator.workersDone[workerId] = false
+
+	readyStepIds := coordinator.steps.Ready()
+	if len(readyStepIds) == 0 {
+		return nil, ErrNoWork
+	}
+
+	stepId := readyStepIds[0]
+	if startErr := coordinator.steps.Start(stepId); startErr != nil {
+		return nil, startErr
+	}
+
+	step := coordinator.job.GetStep(stepId)
+	lease := &Lease{
+		LeaseId:    getLeaseId(),
+		StepId:     stepId,
+		Run:        step.Run,
+		Retries:    step.Retries,
+		BackoffMs:  step.RetryBackoff.Milliseconds(),
+		TimeoutMs:  step.Timeout.Milliseconds(),
+		TtlMs:      coordinator.leaseTtl.Milliseconds(),
+		ExpiresAt:  coordinator.now().Add(coordinator.leaseTtl),
+		WorkerId:   workerId,
+		Precedence: step.Precedence,
+	}
+	coordinator.leasesById[lease.LeaseId] = lease
+
+	return lease, nil
+}
+
+// Extends the lease by another TTL, returning the new expiry. Returns ErrLeaseLost if the lease
+// has already expired.
+func (coordinator *Coordinator) Heartbeat(leaseId string) (time.Time, error) {
+	coordinator.mutex.Lock()
+	defer coordinator.mutex.Unlock()
+
+	coordinator.expireLeases()
+
+	lease, leaseExists := coordinator.leasesById[leaseId]
+	if !leaseExists {
+		return time.Time{}, ErrLeaseLost
+	}
+
+	lease.ExpiresAt = coordinator.now().Add(coordinator.leaseTtl)
+	return lease.ExpiresAt, nil
+}
+
+// Records the result of a leased step and releases the lease. A failed step's dependents are
+// skipped, and unless the coordinator is keeping going no further steps are handed out. Returns
+// ErrLeaseLost if the lease has already expired, in which case the result is ignored.
+func (coordinator *Coordinator) Finish(leaseId string, result scheduler.StepResult) ([]string, error) {
+	coordinator.mutex.Lock()
+	defer coordinator.mutex.Unlock()
+
+	coordinator.expireLeases()
+
+	lease, leaseExists := coordinator.leasesById[leaseId]
+	if !leaseExists {
+		return nil, ErrLeaseLost
+	}
+	delete(coordinator.leasesById, leaseId)
+
+	result.StepId = lease.StepId
+	if result.State != scheduler.StepSucceeded {
+		result.State = scheduler.StepFailed
+	}
+	coordinator.report.Results = append(coordinator.report.Results, result)
+
+	if result.State == scheduler.StepSucceeded {
+		return []string{}, coordinator.steps.Complete(lease.StepId)
+	}
+
+	skipped, failErr := coordinator.steps.Fail(lease.StepId)
+	if failErr != nil {
+		return nil, failErr
+	}
+	coordinator.report.Skipped = append(coordinator.report.Skipped, skipped...)
+	coordinator.stopped = coordinator.stopped || !coordinator.keepGoing
+
+	return skipped, nil
+}
+
+// Reports whether the job is over: nothing is leased and nothing more will be handed out
+func (coordinator *Coordinator) Finished() bool {
+	coordinator.mutex.Lock()
+	defer coordinator.mutex.Unlock()
+
+	coordinator.expireLeases()
+	return coordinator.isFinished()
+}
+
+// Returns each step's state along with lease counts
+func (coordinator *Coordinator) Status() Status {
+	coordinator.mutex.Lock()
+	defer coordinator.mutex.Unlock()
+
+	coordinator.expireLeases()
+
+	states := make(map[string]scheduler.StepState, len(coordinator.job.Steps))
+	for _, step := range coordinator.job.Steps {
+		states[step.StepId], _ = coordinator.steps.State(step.StepId)
+	}
+
+	return Status{
+		States:   states,
+		Leases:   len(coordinator.leasesById),
+		Requeues: coordinator.requeues,
+		Finished: coordinator.isFinished(),
+	}
+}
+
+// Returns the results reported so far, with the steps still pending listed as not run
+func (coordinator *Coordinator) Report() *scheduler.ExecutionReport {
+	coordinator.mutex.Lock()
+	defer coordinator.mutex.Unlock()
+
+	report := &scheduler.ExecutionReport{
+		Results: append([]scheduler.StepResult{}, coordinator.report.Results...),
+		Skipped: append([]string{}, coordinator.report.Skipped...),
+	}
+	for _, step := range coordinator.job.Steps {
+		if state, _ := coordinator.steps.State(step.StepId); state == scheduler.StepPending {
+			report.NotRun = append(report.NotRun, step.StepId)
+		}
+	}
+
+	return report
+}
+
+// Lists the workers that have asked for work but haven't been told the job is done yet, sorted
+// by ID. Workers only find out by asking again, so a server should keep running until this is
+// empty, or until it's waited long enough to assume the rest have gone away.
+func (coordinator *Coordinator) PendingWorkers() []string {
+	coordinator.mutex.Lock()
+	defer coordinator.mutex.Unlock()
+
+	workerIds := make([]string, 0)
+	for workerId, isDone := range coordinator.workersDone {
+		if !isDone {
+			workerIds = append(workerIds, workerId)
+		}
+	}
+	sort.Strings(workerIds)
+
+	return workerIds
+}
+
+func (coordinator *Coordinator) isFinished() bool {
+	return len(coordinator.leasesById) == 0 && (coordinator.stopped || coordinator.steps.Done())
+}
+
+// Expiry is checked whenever the coordinator is used rather than on a timer, which is enough
+// since an expired step only matters once a worker asks for work
+func (coordinator *Coordinator) expireLeases() {
+
+	now := coordinator.now()
+	for leaseId, lease := range coordinator.leasesById {
+		if now.Before(lease.ExpiresAt) {
+			continue
+		}
+
+		delete(coordinator.leasesById, leaseId)
+		coordinator.steps.Requeue(lease.StepId)
+		coordinator.requeues++
+	}
+}
+
+func getLeaseId() string {
+	idBytes := make([]byte, 16)
+	if _, readErr := rand.Read(idBytes); readErr != nil {
+		panic(fmt.Sprintf("could not generate lease ID: %s", readErr.Error()))
+	}
+	return hex.EncodeToString(idBytes)
+}
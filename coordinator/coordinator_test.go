@@ -0,0 +1,354 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AdamBuchen/kurtosis-take-home/scheduler"
+)
+
+func TestLeasesStepsInPrecedenceOrder(t *testing.T) {
+	coordinator, _ := getTestCoordinator(t, diamondJobYaml)
+
+	// Both roots are ready at once, and should be handed out highest precedence first
+	var tests = []struct {
+		expectedStepIds []string
+	}{
+		{[]string{"build api", "build web"}},
+		{[]string{"integration test"}},
+		{[]string{"deploy"}},
+	}
+
+	for i, testCase := range tests {
+		leases := make([]*Lease, 0)
+		for {
+			lease, leaseErr := coordinator.Lease("worker")
+			if errors.Is(leaseErr, ErrNoWork) {
+				break
+			}
+			if leaseErr != nil {
+				t.Fatalf("test %d: received unexpected error: %s", i, leaseErr.Error())
+			}
+			leases = append(leases, lease)
+		}
+
+		leasedStepIds := make([]string, len(leases))
+		for leaseIndex, lease := range leases {
+			leasedStepIds[leaseIndex] = lease.StepId
+			if _, finishErr := coordinator.Finish(lease.LeaseId, scheduler.StepResult{State: scheduler.StepSucceeded}); finishErr != nil {
+				t.Fatalf("test %d: received unexpected error: %s", i, finishErr.Error())
+			}
+		}
+
+		if !areStringSlicesEqual(leasedStepIds, testCase.expectedStepIds) {
+			t.Errorf("test %d: expected leases %v, got %v", i, testCase.expectedStepIds, leasedStepIds)
+		}
+	}
+
+	if _, leaseErr := coordinator.Lease("worker"); !errors.Is(leaseErr, ErrJobDone) {
+		t.Errorf("expected the job to be done, got %v", leaseErr)
+	}
+	if !coordinator.Finished() || !coordinator.Report().Succeeded() {
+		t.Errorf("expected a finished, successful job, got:\n%s", coordinator.Report().String())
+	}
+}
+
+func TestExpiredLeasesAreRequeued(t *testing.T) {
+	coordinator, clock := getTestCoordinator(t, diamondJobYaml)
+
+	firstLease, _ := coordinator.Lease("worker 1")
+
+	// A heartbeat inside the TTL keeps the lease alive past its original expiry
+	clock.advance(DefaultLeaseTtl * 2 / 3)
+	if _, heartbeatErr := coordinator.Heartbeat(firstLease.LeaseId); heartbeatErr != nil {
+		t.Fatalf("received unexpected error: %s", heartbeatErr.Error())
+	}
+	clock.advance(DefaultLeaseTtl * 2 / 3)
+	if _, heartbeatErr := coordinator.Heartbeat(firstLease.LeaseId); heartbeatErr != nil {
+		t.Fatalf("lease should have been extended, got: %s", heartbeatErr.Error())
+	}
+
+	// Without one it expires, and the step goes to the next worker to ask
+	clock.advance(DefaultLeaseTtl)
+	if _, heartbeatErr := coordinator.Heartbeat(firstLease.LeaseId); !errors.Is(heartbeatErr, ErrLeaseLost) {
+		t.Errorf("expected the lease to be lost, got %v", heartbeatErr)
+	}
+
+	secondLease, leaseErr := coordinator.Lease("worker 2")
+	if leaseErr != nil {
+		t.Fatalf("received unexpected error: %s", leaseErr.Error())
+	}
+	if secondLease.StepId != firstLease.StepId || secondLease.LeaseId == firstLease.LeaseId {
+		t.Errorf("expected %s to be leased again under a new lease, got %s", firstLease.StepId, secondLease.StepId)
+	}
+
+	// The original holder can no longer report a result
+	if _, finishErr := coordinator.Finish(firstLease.LeaseId, scheduler.StepResult{State: scheduler.StepSucceeded}); !errors.Is(finishErr, ErrLeaseLost) {
+		t.Errorf("expected the stale lease to be rejected, got %v", finishErr)
+	}
+	if _, finishErr := coordinator.Finish(secondLease.LeaseId, scheduler.StepResult{State: scheduler.StepSucceeded}); finishErr != nil {
+		t.Errorf("received unexpected error: %s", finishErr.Error())
+	}
+
+	if status := coordinator.Status(); status.Requeues != 1 || status.States[firstLease.StepId] != scheduler.StepSucceeded {
+		t.Errorf("unexpected status: %+v", status)
+	}
+}
+
+func TestFailuresStopOrSkipDependents(t *testing.T) {
+	var tests = []struct {
+		opts              []Option
+		expectedCompleted []string
+		expectedSkipped   []string
+		expectedNotRun    []string
+	}{
+		{nil, []string{}, []string{"integration test", "deploy"}, []string{"build web"}},
+		{[]Option{WithKeepGoing()}, []string{"build web"}, []string{"integration test", "deploy"}, []string{}},
+	}
+
+	for i, testCase := range tests {
+		coordinator, _ := getTestCoordinator(t, diamondJobYaml, testCase.opts...)
+
+		// Fail build api, which everything but build web depends on, and succeed anything else handed out
+		for {
+			lease, leaseErr := coordinator.Lease("worker")
+			if leaseErr != nil {
+				break
+			}
+			result := scheduler.StepResult{State: scheduler.StepSucceeded}
+			if lease.StepId == "build api" {
+				result = scheduler.StepResult{State: scheduler.StepFailed, ExitCode: 1, Err: errors.New("exit status 1")}
+			}
+			coordinator.Finish(lease.LeaseId, result)
+		}
+
+		report := coordinator.Report()
+		if !areStringSlicesEqual(report.Failed(), []string{"build api"}) {
+			t.Errorf("test %d: expected build api to fail, got %v", i, report.Failed())
+		}
+		if !areStringSlicesEqual(report.Completed(), testCase.expectedCompleted) {
+			t.Errorf("test %d: expected succeeded %v, got %v", i, testCase.expectedCompleted, report.Completed())
+		}
+		if !areStringSlicesEqual(report.Skipped, testCase.expectedSkipped) {
+			t.Errorf("test %d: expected skipped %v, got %v", i, testCase.expectedSkipped, report.Skipped)
+		}
+		if !areStringSlicesEqual(report.NotRun, testCase.expectedNotRun) {
+			t.Errorf("test %d: expected not run %v, got %v", i, testCase.expectedNotRun, report.NotRun)
+		}
+		if !coordinator.Finished() {
+			t.Errorf("test %d: expected the coordinator to be finished", i)
+		}
+	}
+}
+
+func TestWorkersArePendingUntilTheyHearTheJobIsDone(t *testing.T) {
+	coordinator, clock := getTestCoordinator(t, diamondJobYaml)
+
+	// Worker b takes a step and goes quiet until its lease expires, then worker a runs the job
+	coordinator.Lease("worker b")
+	clock.advance(DefaultLeaseTtl)
+	for {
+		lease, leaseErr := coordinator.Lease("worker a")
+		if leaseErr != nil {
+			break
+		}
+		coordinator.Finish(lease.LeaseId, scheduler.StepResult{State: scheduler.StepSucceeded})
+	}
+	if !coordinator.Finished() {
+		t.Fatalf("expected the job to be finished, got:\n%s", coordinator.Report().String())
+	}
+
+	// Worker a's last ask was answered with ErrJobDone, but worker b only finds out by asking again
+	var tests = []struct {
+		workerId        string
+		expectedPending []string
+	}{
+		{"", []string{"worker b"}},
+		{"worker b", []string{}},
+		{"worker c", []string{}}, // A worker that turns up late hears straight away
+	}
+
+	for i, testCase := range tests {
+		if testCase.workerId != "" {
+			if _, leaseErr := coordinator.Lease(testCase.workerId); !errors.Is(leaseErr, ErrJobDone) {
+				t.Errorf("test %d: expected the job to be done, got %v", i, leaseErr)
+			}
+		}
+		if pending := coordinator.PendingWorkers(); !areStringSlicesEqual(pending, testCase.expectedPending) {
+			t.Errorf("test %d: expected pending workers %v, got %v", i, testCase.expectedPending, pending)
+		}
+	}
+}
+
+func TestInProcessWorkersRunEveryStep(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "coordinator.sock")
+
+	var tests = []struct {
+		listenAddr string
+	}{
+		{"127.0.0.1:0"},
+		{"unix:" + socketPath},
+	}
+
+	for i, testCase := range tests {
+		job := getTestJob(t, wideJobYaml)
+		coordinator, _ := New(context.Background(), job, WithLeaseTtl(150*time.Millisecond))
+
+		listener, listenErr := Listen(testCase.listenAddr)
+		if listenErr != nil {
+			t.Fatalf("test %d: could not listen: %s", i, listenErr.Error())
+		}
+		httpServer := &http.Server{Handler: NewHandler(coordinator)}
+		go httpServer.Serve(listener)
+
+		clientAddr := listener.Addr().String()
+		if listener.Addr().Network() == "unix" {
+			clientAddr = testCase.listenAddr
+		}
+
+		// Each step records when it ran, and takes longer than the lease TTL so heartbeats are needed
+		var eventMutex sync.Mutex
+		startedAt := make(map[string]int)
+		finishedAt := make(map[string]int)
+		eventCount := 0
+		execute := func(ctx context.Context, step *scheduler.Step) scheduler.StepResult {
+			eventMutex.Lock()
+			startedAt[step.StepId] = eventCount
+			eventCount++
+			eventMutex.Unlock()
+
+			select {
+			case <-time.After(200 * time.Millisecond):
+			case <-ctx.Done():
+				return scheduler.StepResult{State: scheduler.StepFailed, Err: ctx.Err()}
+			}
+
+			eventMutex.Lock()
+			finishedAt[step.StepId] = eventCount
+			eventCount++
+			eventMutex.Unlock()
+			return scheduler.StepResult{State: scheduler.StepSucceeded}
+		}
+
+		var waitGroup sync.WaitGroup
+		for worker := 0; worker < 4; worker++ {
+			waitGroup.Add(1)
+			go func(workerId string) {
+				defer waitGroup.Done()
+				if workerErr := RunWorker(context.Background(), NewClient(clientAddr), workerId, execute, 10*time.Millisecond); workerErr != nil {
+					t.Errorf("test %d: worker %s failed: %s", i, workerId, workerErr.Error())
+				}
+			}(string(rune('a' + worker)))
+		}
+		waitGroup.Wait()
+		httpServer.Close()
+
+		report := coordinator.Report()
+		if !report.Succeeded() || len(report.Results) != len(job.Steps) {
+			t.Errorf("test %d: expected every step to succeed, got:\n%s", i, report.String())
+		}
+		if requeues := coordinator.Status().Requeues; requeues != 0 {
+			t.Errorf("test %d: heartbeats should have kept every lease, but %d expired", i, requeues)
+		}
+
+		for _, step := range job.Steps {
+			for _, dependencyId := range step.DependencyIds {
+				if finishedAt[dependencyId] > startedAt[step.StepId] {
+					t.Errorf("test %d: %s started before its dependency %s finished", i, step.StepId, dependencyId)
+				}
+			}
+		}
+	}
+}
+
+// A clock that only moves when told to
+type testClock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+func (clock *testClock) Now() time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	return clock.now
+}
+
+func (clock *testClock) advance(duration time.Duration) {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	clock.now = clock.now.Add(duration)
+}
+
+func getTestCoordinator(t *testing.T, yamlStr string, opts ...Option) (*Coordinator, *testClock) {
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	coordinator, coordinatorErr := New(context.Background(), getTestJob(t, yamlStr), append([]Option{WithClock(clock.Now)}, opts...)...)
+	if coordinatorErr != nil {
+		t.Fatalf("received unexpected error: %s", coordinatorErr.Error())
+	}
+	return coordinator, clock
+}
+
+func getTestJob(t *testing.T, yamlStr string) *scheduler.Job {
+	inputJob, parseErr := scheduler.Parse(yamlStr)
+	if parseErr != nil {
+		t.Fatalf("received unexpected error: %s", parseErr.Error())
+	}
+	job, validateErr := scheduler.Validate(inputJob)
+	if validateErr != nil {
+		t.Fatalf("received unexpected error: %s", validateErr.Error())
+	}
+	return job
+}
+
+func areStringSlicesEqual(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+const diamondJobYaml string = `
+- step: "build web"
+  dependencies: []
+  precedence: 50
+- step: "build api"
+  dependencies: []
+  precedence: 100
+- step: "integration test"
+  dependencies: ["build web", "build api"]
+  precedence: 10
+- step: "deploy"
+  dependencies: ["integration test"]
+  precedence: 10
+`
+
+const wideJobYaml string = `
+- step: "fetch"
+  dependencies: []
+  precedence: 100
+- step: "compile a"
+  dependencies: ["fetch"]
+  precedence: 50
+- step: "compile b"
+  dependencies: ["fetch"]
+  precedence: 50
+- step: "compile c"
+  dependencies: ["fetch"]
+  precedence: 40
+- step: "link"
+  dependencies: ["compile a", "compile b", "compile c"]
+  precedence: 10
+- step: "docs"
+  dependencies: []
+  precedence: 1
+`
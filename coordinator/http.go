@@ -0,0 +1,324 @@
+package coordinator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/AdamBuchen/kurtosis-take-home/scheduler"
+)
+
+// The endpoints served by NewHandler. Every body is JSON.
+//
+//	POST /lease      {"worker"}                  200 with a Lease, 204 if nothing is ready, 410 when done
+//	POST /heartbeat  {"lease"}                   200 {"expires_at"}, 409 if the lease was lost
+//	POST /finish     {"lease", "succeeded", ...} 200 {"skipped"}, 409 if the lease was lost
+//	GET  /status                                 200 with a Status
+const (
+	leasePath     = "/lease"
+	heartbeatPath = "/heartbeat"
+	finishPath    = "/finish"
+	statusPath    = "/status"
+)
+
+type leaseRequest struct {
+	WorkerId string `json:"worker"`
+}
+
+type heartbeatRequest struct {
+	LeaseId string `json:"lease"`
+}
+
+type heartbeatResponse struct {
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// A StepResult as sent over the wire, with its errors flattened to messages
+type finishRequest struct {
+	LeaseId    string           `json:"lease"`
+	Succeeded  bool             `json:"succeeded"`
+	ExitCode   int              `json:"exit_code"`
+	DurationMs int64            `json:"duration_ms"`
+	Message    string           `json:"message,omitempty"`
+	Attempts   []attemptMessage `json:"attempts,omitempty"`
+}
+
+type attemptMessage struct {
+	ExitCode   int    `json:"exit_code"`
+	DurationMs int64  `json:"duration_ms"`
+	Message    string `json:"message,omitempty"`
+	TimedOut   bool   `json:"timed_out,omitempty"`
+}
+
+type finishResponse struct {
+	Skipped []string `json:"skipped"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Returns a handler serving the coordinator's API
+func NewHandler(coordinator *Coordinator) http.Handler {
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc(leasePath, func(writer http.ResponseWriter, request *http.Request) {
+		var body leaseRequest
+		if !decodeRequest(writer, request, &body) {
+			return
+		}
+
+		lease, leaseErr := coordinator.Lease(body.WorkerId)
+		switch {
+		case errors.Is(leaseErr, ErrNoWork):
+			writer.WriteHeader(http.StatusNoContent)
+		case errors.Is(leaseErr, ErrJobDone):
+			writeJson(writer, http.StatusGone, errorResponse{Error: leaseErr.Error()})
+		case leaseErr != nil:
+			writeJson(writer, http.StatusInternalServerError, errorResponse{Error: leaseErr.Error()})
+		default:
+			writeJson(writer, http.StatusOK, lease)
+		}
+	})
+
+	mux.HandleFunc(heartbeatPath, func(writer http.ResponseWriter, request *http.Request) {
+		var body heartbeatRequest
+		if !decodeRequest(writer, request, &body) {
+			return
+		}
+
+		expiresAt, heartbeatErr := coordinator.Heartbeat(body.LeaseId)
+		if heartbeatErr != nil {
+			writeJson(writer, http.StatusConflict, errorResponse{Error: heartbeatErr.Error()})
+			return
+		}
+		writeJson(writer, http.StatusOK, heartbeatResponse{ExpiresAt: expiresAt})
+	})
+
+	mux.HandleFunc(finishPath, func(writer http.ResponseWriter, request *http.Request) {
+		var body finishRequest
+		if !decodeRequest(writer, request, &body) {
+			return
+		}
+
+		skipped, finishErr := coordinator.Finish(body.LeaseId, getStepResult(body))
+		switch {
+		case errors.Is(finishErr, ErrLeaseLost):
+			writeJson(writer, http.StatusConflict, errorResponse{Error: finishErr.Error()})
+		case finishErr != nil:
+			writeJson(writer, http.StatusInternalServerError, errorResponse{Error: finishErr.Error()})
+		default:
+			writeJson(writer, http.StatusOK, finishResponse{Skipped: skipped})
+		}
+	})
+
+	mux.HandleFunc(statusPath, func(writer http.ResponseWriter, request *http.Request) {
+		if request.Method != http.MethodGet {
+			writer.Header().Set("Allow", "GET")
+			writeJson(writer, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed, use GET"})
+			return
+		}
+		writeJson(writer, http.StatusOK, coordinator.Status())
+	})
+
+	return mux
+}
+
+// Listens on a TCP address (host:port) or, given "unix:<path>", a Unix socket. A socket file left
+// behind by an earlier coordinator is replaced.
+func Listen(addr string) (net.Listener, error) {
+
+	if !strings.HasPrefix(addr, "unix:") {
+		return net.Listen("tcp", addr)
+	}
+
+	socketPath := strings.TrimPrefix(addr, "unix:")
+
+	if info, statErr := os.Stat(socketPath); statErr == nil && info.Mode().Type() == fs.ModeSocket {
+		os.Remove(socketPath)
+	}
+	return net.Listen("unix", socketPath)
+}
+
+// Talks to a coordinator on behalf of a worker
+type Client struct {
+	baseUrl    string
+	httpClient *http.Client
+}
+
+// Returns a client for the coordinator at addr, which is a URL, a host:port, or "unix:<path>"
+// for a Unix socket
+func NewClient(addr string) *Client {
+
+	if strings.HasPrefix(addr, "unix:") {
+		socketPath := strings.TrimPrefix(addr, "unix:")
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, _ string, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socketPath)
+			},
+		}
+		return &Client{baseUrl: "http://coordinator", httpClient: &http.Client{Transport: transport}}
+	}
+
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	return &Client{baseUrl: strings.TrimSuffix(addr, "/"), httpClient: &http.Client{}}
+}
+
+// Asks for the next ready step. Returns ErrNoWork or ErrJobDone like Coordinator.Lease.
+func (client *Client) Lease(ctx context.Context, workerId string) (*Lease, error) {
+
+	lease := &Lease{}
+	status, postErr := client.post(ctx, leasePath, leaseRequest{WorkerId: workerId}, lease)
+	switch {
+	case postErr != nil:
+		return nil, postErr
+	case status == http.StatusNoContent:
+		return nil, ErrNoWork
+	case status == http.StatusGone:
+		return nil, ErrJobDone
+	}
+
+	return lease, nil
+}
+
+// Extends the lease. Returns ErrLeaseLost if it has already expired.
+func (client *Client) Heartbeat(ctx context.Context, leaseId string) error {
+	_, postErr := client.post(ctx, heartbeatPath, heartbeatRequest{LeaseId: leaseId}, &heartbeatResponse{})
+	return postErr
+}
+
+// Reports the step's result, returning the steps skipped because of a failure. Returns
+// ErrLeaseLost if the lease had already expired.
+func (client *Client) Finish(ctx context.Context, leaseId string, result scheduler.StepResult) ([]string, error) {
+
+	response := &finishResponse{}
+	if _, postErr := client.post(ctx, finishPath, getFinishRequest(leaseId, result), response); postErr != nil {
+		return nil, postErr
+	}
+
+	return response.Skipped, nil
+}
+
+// Posts a JSON body and decodes a 200 response into responseBody. 204 and 410 are returned as
+// statuses for the caller to interpret, and 409 as ErrLeaseLost.
+func (client *Client) post(ctx context.Context, path string, requestBody interface{}, responseBody interface{}) (int, error) {
+
+	encoded, marshalErr := json.Marshal(requestBody)
+	if marshalErr != nil {
+		return 0, marshalErr
+	}
+
+	request, requestErr := http.NewRequestWithContext(ctx, http.MethodPost, client.baseUrl+path, bytes.NewReader(encoded))
+	if requestErr != nil {
+		return 0, requestErr
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, postErr := client.httpClient.Do(request)
+	if postErr != nil {
+		return 0, postErr
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK:
+		return response.StatusCode, json.NewDecoder(response.Body).Decode(responseBody)
+	case http.StatusNoContent, http.StatusGone:
+		return response.StatusCode, nil
+	case http.StatusConflict:
+		return response.StatusCode, ErrLeaseLost
+	}
+
+	var errorBody errorResponse
+	json.NewDecoder(response.Body).Decode(&errorBody)
+	return response.StatusCode, fmt.Errorf("coordinator returned %d: %s", response.StatusCode, errorBody.Error)
+}
+
+func getFinishRequest(leaseId string, result scheduler.StepResult) finishRequest {
+
+	request := finishRequest{
+		LeaseId:    leaseId,
+		Succeeded:  result.State == scheduler.StepSucceeded,
+		ExitCode:   result.ExitCode,
+		DurationMs: result.Duration.Milliseconds(),
+		Message:    getErrorMessage(result.Err),
+	}
+	for _, attempt := range result.Attempts {
+		request.Attempts = append(request.Attempts, attemptMessage{
+			ExitCode:   attempt.ExitCode,
+			DurationMs: attempt.Duration.Milliseconds(),
+			Message:    getErrorMessage(attempt.Err),
+			TimedOut:   attempt.TimedOut,
+		})
+	}
+
+	return request
+}
+
+func getStepResult(request finishRequest) scheduler.StepResult {
+
+	result := scheduler.StepResult{
+		State:    scheduler.StepSucceeded,
+		ExitCode: request.ExitCode,
+		Duration: time.Duration(request.DurationMs) * time.Millisecond,
+	}
+	if !request.Succeeded {
+		result.State = scheduler.StepFailed
+		result.Err = errors.New(request.Message)
+	}
+	for _, attempt := range request.Attempts {
+		stepAttempt := scheduler.StepAttempt{
+			ExitCode: attempt.ExitCode,
+			Duration: time.Duration(attempt.DurationMs) * time.Millisecond,
+			TimedOut: attempt.TimedOut,
+		}
+		if attempt.Message != "" {
+			stepAttempt.Err = errors.New(attempt.Message)
+		}
+		result.Attempts = append(result.Attempts, stepAttempt)
+	}
+
+	return result
+}
+
+func getErrorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// Decodes a POSTed JSON body, writing an error response and returning false if it can't
+func decodeRequest(writer http.ResponseWriter, request *http.Request, body interface{}) bool {
+
+	if request.Method != http.MethodPost {
+		writer.Header().Set("Allow", "POST")
+		writeJson(writer, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed, use POST"})
+		return false
+	}
+
+	if decodeErr := json.NewDecoder(http.MaxBytesReader(writer, request.Body, 1<<20)).Decode(body); decodeErr != nil {
+		writeJson(writer, http.StatusBadRequest, errorResponse{Error: "invalid request body: " + decodeErr.Error()})
+		return false
+	}
+
+	return true
+}
+
+func writeJson(writer http.ResponseWriter, status int, body interface{}) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	json.NewEncoder(writer).Encode(body)
+}
@@ -0,0 +1,88 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/AdamBuchen/kurtosis-take-home/scheduler"
+)
+
+// Runs a leased step, e.g. scheduler.ExecuteStep. It should stop promptly once ctx is done,
+// which happens if the lease is lost.
+type StepExecutor func(ctx context.Context, step *scheduler.Step) scheduler.StepResult
+
+// How long a worker waits before asking again when nothing is ready
+const DefaultPollInterval = 500 * time.Millisecond
+
+// Leases steps from the coordinator and runs them one at a time until the job is done or ctx is
+// cancelled. While a step runs the lease is renewed every third of its TTL. If the lease is lost
+// anyway, the step is cancelled and its result dropped, since another worker may have it by then.
+func RunWorker(ctx context.Context, client *Client, workerId string, execute StepExecutor, pollInterval time.Duration) error {
+
+	for {
+		lease, leaseErr := client.Lease(ctx, workerId)
+		switch {
+		case errors.Is(leaseErr, ErrJobDone):
+			return nil
+		case errors.Is(leaseErr, ErrNoWork):
+			select {
+			case <-time.After(pollInterval):
+				continue
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		case leaseErr != nil:
+			return leaseErr
+		}
+
+		result, leaseLost := runLeasedStep(ctx, client, lease, execute)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if leaseLost {
+			continue
+		}
+
+		if _, finishErr := client.Finish(ctx, lease.LeaseId, result); finishErr != nil && !errors.Is(finishErr, ErrLeaseLost) {
+			return finishErr
+		}
+	}
+}
+
+// Runs the step while heartbeating in the background, reporting whether the lease was lost
+func runLeasedStep(ctx context.Context, client *Client, lease *Lease, execute StepExecutor) (scheduler.StepResult, bool) {
+
+	stepCtx, cancelStep := context.WithCancel(ctx)
+	defer cancelStep()
+
+	leaseLost := make(chan bool, 1)
+	stopHeartbeat := make(chan struct{})
+	go func() {
+		interval := time.Duration(lease.TtlMs) * time.Millisecond / 3
+		if interval <= 0 {
+			interval = time.Second
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if heartbeatErr := client.Heartbeat(stepCtx, lease.LeaseId); errors.Is(heartbeatErr, ErrLeaseLost) {
+					leaseLost <- true
+					cancelStep()
+					return
+				}
+			case <-stopHeartbeat:
+				leaseLost <- false
+				return
+			}
+		}
+	}()
+
+	result := execute(stepCtx, lease.GetStep())
+	close(stopHeartbeat)
+
+	return result, <-leaseLost
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"os"
+
+	"github.com/AdamBuchen/kurtosis-take-home/scheduler"
+)
+
+// What kind of failure ended the process. Each class has its own exit code, and is recorded in
+// the --error-report file.
+const (
+	errorClassFailed     = "failed"     // The command ran but found problems, e.g. lint errors or failed steps
+	errorClassUsage      = "usage"      // Bad flags or arguments
+	errorClassIo         = "io"         // A file couldn't be read or written, or a socket couldn't be used
+	errorClassParse      = "parse"      // The input isn't YAML or isn't a list of steps
+	errorClassValidation = "validation" // The steps break one of the job rules
+	errorClassCycle      = "cycle"      // A circular dependency means there's no ordering
+	errorClassLimit      = "limit"      // The input exceeds one of the resource limits
+	errorClassTimeout    = "timeout"    // Scheduling took longer than --timeout
+	errorClassInternal   = "internal"   // Anything else, which is a bug
+)
+
+// The exit code for each error class, as documented in the README. 2 matches what the flag
+// package uses when it rejects a flag itself. Timeout came after the others, so it's last rather
+// than renumbering them.
+var exitCodesByErrorClass = map[string]int{
+	errorClassFailed:     1,
+	errorClassUsage:      2,
+	errorClassIo:         3,
+	errorClassParse:      4,
+	errorClassValidation: 5,
+	errorClassCycle:      6,
+	errorClassLimit:      7,
+	errorClassInternal:   8,
+	errorClassTimeout:    9,
+}
+
+// Where to write a JSON description of the error that ended the process, if anywhere
+var errorReportPath string
+
+// The contents of the --error-report file
+type errorReport struct {
+	Class    string   `json:"class"`
+	ExitCode int      `json:"exit_code"`
+	Message  string   `json:"message"`
+	Limit    string   `json:"limit,omitempty"` // The flag name of the limit hit, for the limit class
+	Steps    []string `json:"steps,omitempty"` // The steps the problem was found on, where they're known
+}
+
+// An error caused by how the command was invoked rather than by the job
+type usageError struct {
+	message string
+}
+
+func (usageErr *usageError) Error() string {
+	return usageErr.message
+}
+
+// Exits for a problem with the command line
+func handleUsageError(errStr string) {
+	exitWithError(errorReport{Class: errorClassUsage, Message: errStr})
+}
+
+// Exits because the command ran but its answer is no: violations, lint errors, failed steps or
+// jobs. steps lists the steps responsible, if any.
+func handleFailure(errStr string, steps []string) {
+	exitWithError(errorReport{Class: errorClassFailed, Message: errStr, Steps: steps})
+}
+
+// Exits for err, with the exit code for its class. message says what was being attempted.
+func handleFatalError(message string, err error) {
+	report := getErrorReport(err)
+	report.Message = message + ": " + err.Error()
+	exitWithError(report)
+}
+
+// Classifies an error by the types the scheduler returns, or failing that by the standard
+// library's file, rename, system call and network errors
+func getErrorReport(err error) errorReport {
+
+	var usageErr *usageError
+	var parseErr *scheduler.ParseError
+	var limitErr *scheduler.LimitError
+	var validationErr *scheduler.ValidationError
+	var cycleErr *scheduler.CycleError
+	var pathErr *fs.PathError
+	var linkErr *os.LinkError
+	var syscallErr *os.SyscallError
+	var netErr *net.OpError
+
+	report := errorReport{Message: err.Error()}
+
+	switch {
+	case errors.As(err, &usageErr):
+		report.Class = errorClassUsage
+	case errors.As(err, &parseErr):
+		report.Class = errorClassParse
+	case errors.As(err, &limitErr):
+		report.Class, report.Limit = errorClassLimit, limitErr.Limit
+	case errors.As(err, &validationErr):
+		report.Class, report.Steps = errorClassValidation, validationErr.StepIds
+	case errors.As(err, &cycleErr):
+		report.Class, report.Steps = errorClassCycle, cycleErr.StepIds
+	case errors.Is(err, context.DeadlineExceeded):
+		report.Class = errorClassTimeout
+	case errors.As(err, &pathErr), errors.As(err, &linkErr), errors.As(err, &syscallErr), errors.As(err, &netErr):
+		report.Class = errorClassIo
+	default:
+		report.Class = errorClassInternal
+	}
+
+	return report
+}
+
+// Prints the error to stderr, writes the error report if one was asked for, and exits
+func exitWithError(report errorReport) {
+
+	report.ExitCode = exitCodesByErrorClass[report.Class]
+
+	logAttrs := []any{"class", report.Class, "exit_code", report.ExitCode, "error", report.Message}
+	if len(report.Steps) > 0 {
+		logAttrs = append(logAttrs, "steps", report.Steps)
+	}
+	// The error itself is always printed below, so the log record is only for runs that asked for
+	// structured progress logs with --log-level info or debug, and is hidden at the default level
+	slog.Info("run failed", logAttrs...)
+
+	fmt.Fprintln(os.Stderr, "error detected: "+report.Message)
+	fmt.Fprintf(os.Stderr, "error code: %d\n", report.ExitCode)
+
+	removeOutputAfterError(runOutputPath)
+
+	if errorReportPath != "" {
+		if reportErr := writeErrorReport(report); reportErr != nil {
+			fmt.Fprintln(os.Stderr, "could not write error report: "+reportErr.Error())
+		}
+	}
+
+	os.Exit(report.ExitCode)
+}
+
+func writeErrorReport(report errorReport) error {
+
+	encoded, marshalErr := json.MarshalIndent(report, "", "  ")
+	if marshalErr != nil {
+		return marshalErr
+	}
+
+	return writeStringToFile(string(encoded)+"\n", errorReportPath)
+}
+
+// Removes a report left by an earlier run, so that a report on disk always describes this one
+func clearErrorReport() {
+	if errorReportPath == "" {
+		return
+	}
+
+	if removeErr := os.Remove(errorReportPath); removeErr != nil && !errors.Is(removeErr, fs.ErrNotExist) {
+		handleFatalError("could not remove old error report", removeErr)
+	}
+}
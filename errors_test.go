@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/AdamBuchen/kurtosis-take-home/scheduler"
+)
+
+// The exit codes are documented in the README, and CI callers depend on them
+func TestErrorsMapToDocumentedExitCodes(t *testing.T) {
+	var tests = []struct {
+		err           error
+		expectedClass string
+		expectedCode  int
+		expectedLimit string
+		expectedSteps []string
+	}{
+		{&usageError{message: "bad flag"}, errorClassUsage, 2, "", nil},
+		{&fs.PathError{Op: "open", Path: "job.yml", Err: fs.ErrNotExist}, errorClassIo, 3, "", nil},
+		{fmt.Errorf("could not write out file: %w", &os.LinkError{Op: "rename", Old: ".ordering.txt.tmp-1", New: "ordering.txt", Err: syscall.EBUSY}), errorClassIo, 3, "", nil},
+		{os.NewSyscallError("fsync", syscall.EIO), errorClassIo, 3, "", nil},
+		{&net.OpError{Op: "listen", Net: "tcp", Err: errors.New("address already in use")}, errorClassIo, 3, "", nil},
+		{&scheduler.ParseError{Err: errors.New("yaml: line 1")}, errorClassParse, 4, "", nil},
+		{&scheduler.ValidationError{StepIds: []string{"deploy"}, Message: "invalid dependency"}, errorClassValidation, 5, "", []string{"deploy"}},
+		{fmt.Errorf("new job: %w", &scheduler.CycleError{StepIds: []string{"build", "deploy"}}), errorClassCycle, 6, "", []string{"build", "deploy"}},
+		{&scheduler.LimitError{Limit: "max-steps", Message: "too many steps"}, errorClassLimit, 7, "max-steps", nil},
+		{fmt.Errorf("scheduling stopped: %w", context.DeadlineExceeded), errorClassTimeout, 9, "", nil},
+		{errors.New("something unexpected"), errorClassInternal, 8, "", nil},
+	}
+
+	for i, testCase := range tests {
+		report := getErrorReport(testCase.err)
+
+		if report.Class != testCase.expectedClass || exitCodesByErrorClass[report.Class] != testCase.expectedCode {
+			t.Errorf("test %d: expected %s (%d), got %s (%d)", i, testCase.expectedClass, testCase.expectedCode, report.Class, exitCodesByErrorClass[report.Class])
+		}
+		if report.Limit != testCase.expectedLimit {
+			t.Errorf("test %d: expected limit %q, got %q", i, testCase.expectedLimit, report.Limit)
+		}
+		if !areStringSlicesEqual(report.Steps, testCase.expectedSteps) {
+			t.Errorf("test %d: expected steps %v, got %v", i, testCase.expectedSteps, report.Steps)
+		}
+		if report.Message != testCase.err.Error() {
+			t.Errorf("test %d: expected message %q, got %q", i, testCase.err.Error(), report.Message)
+		}
+	}
+
+	// handleFailure reports the failed class directly rather than through getErrorReport
+	if exitCodesByErrorClass[errorClassFailed] != 1 {
+		t.Errorf("expected failures to exit with 1, got %d", exitCodesByErrorClass[errorClassFailed])
+	}
+
+	// Every class needs its own code, or callers can't tell them apart
+	classesByExitCode := make(map[int]string, len(exitCodesByErrorClass))
+	for class, exitCode := range exitCodesByErrorClass {
+		if otherClass, isTaken := classesByExitCode[exitCode]; isTaken {
+			t.Errorf("%s and %s share exit code %d", class, otherClass, exitCode)
+		}
+		classesByExitCode[exitCode] = class
+	}
+}
@@ -1,5 +1,8 @@
 module github.com/AdamBuchen/kurtosis-take-home
 
-go 1.19
+go 1.21
 
-require gopkg.in/yaml.v3 v3.0.1 // indirect
+require (
+	golang.org/x/text v0.14.0
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/AdamBuchen/kurtosis-take-home/scheduler"
+)
+
+// The resource limits in effect for this process, overridden from the command line
+var resourceLimits = scheduler.DefaultResourceLimits()
+
+// Registers a flag for each limit on the given flag set, defaulting to the current values
+func registerResourceLimitFlags(flags *flag.FlagSet, limits *scheduler.ResourceLimits) {
+	flags.Int64Var(&limits.MaxInputBytes, "max-input-bytes", limits.MaxInputBytes, "largest input file to read, 0 for no cap")
+	flags.IntVar(&limits.MaxSteps, "max-steps", limits.MaxSteps, "most steps a job may have, 0 for no cap")
+	flags.IntVar(&limits.MaxDependenciesPerStep, "max-dependencies", limits.MaxDependenciesPerStep, "most dependencies a step may have, 0 for no cap")
+	flags.IntVar(&limits.MaxStepIdLength, "max-id-length", limits.MaxStepIdLength, "longest step ID in characters, 0 for no cap")
+	flags.IntVar(&limits.MaxAliasExpansions, "max-alias-expansions", limits.MaxAliasExpansions, "most YAML aliases resolved while decoding, 0 for no cap")
+	flags.DurationVar(&limits.Timeout, "timeout", limits.Timeout, "longest scheduling may take, 0 for no timeout")
+}
+
+// The options every call into the scheduler package is made with
+func getSchedulerOptions() []scheduler.Option {
+	return []scheduler.Option{scheduler.WithLimits(resourceLimits)}
+}
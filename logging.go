@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/AdamBuchen/kurtosis-take-home/scheduler"
+)
+
+// Replaces the default logger with one writing to stderr at the given level, as text or JSON
+func configureLogging(levelName string, format string) error {
+
+	var level slog.Level
+	if levelErr := level.UnmarshalText([]byte(levelName)); levelErr != nil {
+		return fmt.Errorf("unknown log level: %s (use debug, info, warn or error)", levelName)
+	}
+
+	handlerOptions := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch format {
+	case "text":
+		handler = slog.NewTextHandler(os.Stderr, handlerOptions)
+	case "json":
+		handler = slog.NewJSONHandler(os.Stderr, handlerOptions)
+	default:
+		return fmt.Errorf("unknown log format: %s (use text or json)", format)
+	}
+
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// Logs that a phase of a run finished, with how long it took in milliseconds so that log
+// aggregators can sum and chart it
+func logPhase(logger *slog.Logger, phase string, startTime time.Time, attrs ...any) {
+	attrs = append([]any{"phase", phase, "duration_ms", getMilliseconds(time.Since(startTime))}, attrs...)
+	logger.Info("phase finished", attrs...)
+}
+
+func getMilliseconds(duration time.Duration) float64 {
+	return float64(duration.Microseconds()) / 1000
+}
+
+// Counts the dependency edges in the job
+func getEdgeCount(job *scheduler.Job) int {
+
+	edgeCount := 0
+	for _, step := range job.Steps {
+		edgeCount += len(step.DependencyIds)
+	}
+
+	return edgeCount
+}
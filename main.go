@@ -1,46 +1,138 @@
 package main
 
 import (
+	"context"
 	"flag"
-	"strings"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/AdamBuchen/kurtosis-take-home/scheduler"
 )
 
 func main() {
 
+	// Optional file of step IDs that already ran, for resuming a pipeline that died partway through
+	completedPath := flag.String("completed", "", "path to a newline-delimited list of already-completed step IDs")
+
+	// Reschedule whenever the input file changes, for use while authoring a job
+	watch := flag.Bool("watch", false, "keep running, rescheduling and printing a diff every time the input file changes")
+	watchInterval := flag.Duration("watch-interval", 500*time.Millisecond, "how often --watch checks the input file for changes")
+
+	// Where to describe a failure as JSON, for CI bots to pick up
+	flag.StringVar(&errorReportPath, "error-report", "", "on failure, write the error class, message and affected steps to this JSON file")
+
+	// Structured logs on stderr, for diagnosing slow or failed runs
+	logLevel := flag.String("log-level", "warn", "least severe log level to write: debug, info, warn or error")
+	logFormat := flag.String("log-format", "text", "log format: text or json")
+
+	// How ordering files are written
+	flag.Var(&outputFileMode, "output-mode", "permissions for ordering files, in octal")
+	flag.BoolVar(&removeOutputOnError, "remove-output-on-error", false, "delete the ordering file if the run fails, instead of leaving it untouched")
+
+	// Caps on input size, step counts, alias expansion and processing time
+	registerResourceLimitFlags(flag.CommandLine, &resourceLimits)
+
 	// Read in command-line flags, which should be our yml input and txt output paths, respectively
 	flag.Parse()
+	if loggingErr := configureLogging(*logLevel, *logFormat); loggingErr != nil {
+		handleUsageError(loggingErr.Error())
+	}
+	clearErrorReport()
+
+	// Subcommands are picked out by the first positional argument
+	if command, isCommand := commands[flag.Arg(0)]; isCommand {
+		command(flag.Args()[1:])
+		return
+	}
+
 	inputPath := flag.Arg(0)
 	outputPath := flag.Arg(1)
 	if inputPath == "" || outputPath == "" {
-		handleFatalError("two input arguments required")
+		handleUsageError("two input arguments required")
 	}
 
+	runOutputPath = outputPath
+
+	if *watch {
+		if *completedPath != "" {
+			handleUsageError("--watch can't be combined with --completed")
+		}
+		if *watchInterval <= 0 {
+			handleUsageError("--watch-interval must be greater than zero")
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		watchUserJob(ctx, inputPath, outputPath, *watchInterval)
+		return
+	}
+
+	runStartTime := time.Now()
+	runLogger := slog.With("input", inputPath)
+
 	// Read in Yaml string from input path
+	phaseStartTime := time.Now()
 	yamlStr, fileReadErr := getStringFromPath(inputPath)
 	if fileReadErr != nil {
-		handleFatalError("could not open input path: " + fileReadErr.Error())
+		handleFatalError("could not open input path", fileReadErr)
 	}
+	logPhase(runLogger, "read", phaseStartTime, "bytes", len(yamlStr))
 
-	// Actually process the user job. If successful, gets back a []string that can be inserted into
-	// the file at outputPath. This is where the heavy lifting is, and there's a clear interface (YAML in, output []line out)
-	// that this is also where our testing can happen, at this interface boundary.
-	outputLines, processingErr := ProcessUserJob(yamlStr)
-	if processingErr != nil {
-		handleFatalError("could not process user job: " + processingErr.Error())
+	// Actually process the user job, one stage at a time so each can be timed. If successful, gets
+	// back a []string that can be inserted into the file at outputPath. This is where the heavy
+	// lifting is, and there's a clear interface (YAML in, output []line out) that this is also where
+	// our testing can happen, at this interface boundary.
+	phaseStartTime = time.Now()
+	inputJob, parseErr := scheduler.Parse(yamlStr, getSchedulerOptions()...)
+	if parseErr != nil {
+		handleFatalError("could not process user job", parseErr)
 	}
+	logPhase(runLogger, "parse", phaseStartTime, "steps", len(inputJob.Steps))
 
-	// Per instructions: An output ordering is always terminated by a newline
-	var builder strings.Builder
-	for _, line := range outputLines {
-		builder.WriteString(line + "\n")
+	phaseStartTime = time.Now()
+	job, validateErr := scheduler.Validate(inputJob)
+	if validateErr != nil {
+		handleFatalError("could not process user job", validateErr)
 	}
+	logPhase(runLogger, "validate", phaseStartTime, "steps", len(job.Steps), "edges", getEdgeCount(job))
+
+	ctx, cancel := scheduler.NewProcessingContext(resourceLimits)
+	defer cancel()
+
+	var outputLines []string
+	var scheduleErr error
+	if *completedPath == "" {
+		phaseStartTime = time.Now()
+		outputLines, scheduleErr = scheduler.Schedule(ctx, job)
+	} else {
+		completedIds, completedReadErr := getStepIdsFromPath(*completedPath)
+		if completedReadErr != nil {
+			handleFatalError("could not open completed path", completedReadErr)
+		}
 
-	outputStr := builder.String()
+		phaseStartTime = time.Now()
+		var warnings []string
+		outputLines, warnings, scheduleErr = scheduler.Resume(ctx, job, completedIds)
+		for _, warning := range warnings {
+			runLogger.Warn(warning)
+		}
+	}
+	if scheduleErr != nil {
+		handleFatalError("could not process user job", scheduleErr)
+	}
+	logPhase(runLogger, "schedule", phaseStartTime, "scheduled_steps", len(outputLines))
 
 	// Write the resulting lines of text to the file at outputPath
-	saveErr := writeStringToFile(outputStr, outputPath)
+	phaseStartTime = time.Now()
+	saveErr := writeOrderingToFile(outputLines, outputPath)
 	if saveErr != nil {
-		handleFatalError("could not write out file: " + saveErr.Error())
+		handleFatalError("could not write out file", saveErr)
 	}
+	logPhase(runLogger, "write", phaseStartTime, "output", outputPath)
 
+	runLogger.Info("run finished", "duration_ms", getMilliseconds(time.Since(runStartTime)), "steps", len(job.Steps), "edges", getEdgeCount(job))
 }
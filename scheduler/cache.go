@@ -0,0 +1,265 @@
+package scheduler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Whether a step's outputs came from the cache
+type CacheStatus string
+
+const (
+	CacheHit  CacheStatus = "hit"
+	CacheMiss CacheStatus = "miss"
+)
+
+// Bumped whenever the key or entry layout changes, so old entries are never misread
+const cacheFormatVersion = "1"
+
+// A local directory of step outputs, keyed by a hash of everything that went into producing them.
+// Each entry is a directory named for its key, holding a manifest and a copy of each output file.
+type stepCache struct {
+	dir string
+
+	// State for the current run: each step's key, and for steps that ran instead of hitting the
+	// cache (or depend on one that did), the step whose miss caused it
+	keysById      map[string]string
+	invalidatedBy map[string]string
+}
+
+// What a cache entry holds, stored alongside the output files themselves
+type cacheManifest struct {
+	StepId string              `json:"step"`
+	Files  []cacheManifestFile `json:"files"`
+}
+
+type cacheManifestFile struct {
+	Path string      `json:"path"`
+	Mode fs.FileMode `json:"mode"`
+}
+
+// Only steps that run something and declare what they read or write are cached. Anything else
+// could have side effects the cache can't see, like a deploy.
+func isCacheable(step *Step) bool {
+	return step.Run != nil && (len(step.Inputs) > 0 || len(step.Outputs) > 0)
+}
+
+// Hashes the step's command, environment, declared inputs and outputs, and the keys of its
+// dependencies. Input globs are hashed by the paths and contents of the files they match.
+func getStepCacheKey(step *Step, cacheKeysById map[string]string) (string, error) {
+
+	hasher := sha256.New()
+	writeField := func(name string, value string) {
+		fmt.Fprintf(hasher, "%s %d %s\n", name, len(value), value)
+	}
+
+	writeField("version", cacheFormatVersion)
+	if step.Run != nil {
+		writeField("command", step.Run.Command)
+		for _, arg := range step.Run.Args {
+			writeField("arg", arg)
+		}
+		for _, envVar := range getSortedEnv(step.Run.Env) {
+			writeField("env", envVar)
+		}
+	}
+
+	for _, output := range step.Outputs {
+		writeField("output", output)
+	}
+
+	for _, pattern := range step.Inputs {
+		writeField("input", pattern)
+
+		inputPaths, inputErr := getMatchingFiles(pattern)
+		if inputErr != nil {
+			return "", inputErr
+		}
+		for _, inputPath := range inputPaths {
+			fileHash, hashErr := getFileHash(inputPath)
+			if hashErr != nil {
+				return "", hashErr
+			}
+			writeField("file", inputPath)
+			writeField("sha256", fileHash)
+		}
+	}
+
+	dependencyIds := append([]string{}, step.DependencyIds...)
+	sort.Strings(dependencyIds)
+	for _, dependencyId := range dependencyIds {
+		writeField("dependency", cacheKeysById[dependencyId])
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// Returns the manifest for the key, or false if there's no entry for it
+func (cache *stepCache) lookup(key string) (*cacheManifest, bool) {
+
+	manifestBytes, readErr := os.ReadFile(filepath.Join(cache.dir, key, "manifest.json"))
+	if readErr != nil {
+		return nil, false
+	}
+
+	manifest := &cacheManifest{}
+	if unmarshalErr := json.Unmarshal(manifestBytes, manifest); unmarshalErr != nil {
+		return nil, false
+	}
+
+	return manifest, true
+}
+
+// Copies an entry's files back to where the step originally wrote them
+func (cache *stepCache) restore(key string, manifest *cacheManifest) error {
+	for _, file := range manifest.Files {
+		if copyErr := copyFile(filepath.Join(cache.dir, key, "files", file.Path), file.Path, file.Mode); copyErr != nil {
+			return copyErr
+		}
+	}
+	return nil
+}
+
+// Saves copies of the step's outputs under the key. The entry is assembled in a temporary
+// directory and renamed into place, so a half-written entry is never seen as a hit.
+func (cache *stepCache) store(key string, step *Step) error {
+
+	manifest := cacheManifest{StepId: step.StepId, Files: make([]cacheManifestFile, 0)}
+	for _, pattern := range step.Outputs {
+		outputPaths, outputErr := getMatchingFiles(pattern)
+		if outputErr != nil {
+			return outputErr
+		}
+		if len(outputPaths) == 0 {
+			return fmt.Errorf("output %s was not produced", pattern)
+		}
+		for _, outputPath := range outputPaths {
+			info, statErr := os.Stat(outputPath)
+			if statErr != nil {
+				return statErr
+			}
+			manifest.Files = append(manifest.Files, cacheManifestFile{Path: outputPath, Mode: info.Mode().Perm()})
+		}
+	}
+
+	if mkdirErr := os.MkdirAll(cache.dir, 0777); mkdirErr != nil {
+		return mkdirErr
+	}
+	tempDir, tempErr := os.MkdirTemp(cache.dir, "tmp-")
+	if tempErr != nil {
+		return tempErr
+	}
+	defer os.RemoveAll(tempDir)
+
+	for _, file := range manifest.Files {
+		if copyErr := copyFile(file.Path, filepath.Join(tempDir, "files", file.Path), file.Mode); copyErr != nil {
+			return copyErr
+		}
+	}
+
+	manifestBytes, marshalErr := json.MarshalIndent(manifest, "", "  ")
+	if marshalErr != nil {
+		return marshalErr
+	}
+	if writeErr := os.WriteFile(filepath.Join(tempDir, "manifest.json"), manifestBytes, 0666); writeErr != nil {
+		return writeErr
+	}
+
+	// Another run may have stored the same key in the meantime, which is just as good
+	renameErr := os.Rename(tempDir, filepath.Join(cache.dir, key))
+	if _, exists := cache.lookup(key); renameErr != nil && !exists {
+		return renameErr
+	}
+	return nil
+}
+
+// Expands a glob into the files it matches, walking into any matched directories. Paths are
+// sorted so the result doesn't depend on directory order.
+func getMatchingFiles(pattern string) ([]string, error) {
+
+	matches, globErr := filepath.Glob(pattern)
+	if globErr != nil {
+		return nil, globErr
+	}
+
+	files := make([]string, 0)
+	for _, match := range matches {
+		walkErr := filepath.WalkDir(match, func(walkPath string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.Type().IsRegular() {
+				files = append(files, walkPath)
+			}
+			return nil
+		})
+		if walkErr != nil {
+			return nil, walkErr
+		}
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+func getFileHash(path string) (string, error) {
+
+	file, openErr := os.Open(path)
+	if openErr != nil {
+		return "", openErr
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	if _, copyErr := io.Copy(hasher, file); copyErr != nil {
+		return "", copyErr
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+func copyFile(sourcePath string, destinationPath string, mode fs.FileMode) error {
+
+	source, openErr := os.Open(sourcePath)
+	if openErr != nil {
+		return openErr
+	}
+	defer source.Close()
+
+	if mkdirErr := os.MkdirAll(filepath.Dir(destinationPath), 0777); mkdirErr != nil {
+		return mkdirErr
+	}
+
+	destination, createErr := os.OpenFile(destinationPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if createErr != nil {
+		return createErr
+	}
+
+	// The mode only applies on create, and the destination may already exist
+	if chmodErr := destination.Chmod(mode); chmodErr != nil {
+		destination.Close()
+		return chmodErr
+	}
+
+	if _, copyErr := io.Copy(destination, source); copyErr != nil {
+		destination.Close()
+		return copyErr
+	}
+
+	return destination.Close()
+}
+
+// Returns whether a declared output path stays inside the working directory, since restoring
+// it from the cache writes there
+func isLocalPath(path string) bool {
+	cleaned := filepath.Clean(path)
+	return !filepath.IsAbs(cleaned) && cleaned != ".." && !strings.HasPrefix(cleaned, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,222 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCacheSkipsUnchangedSteps(t *testing.T) {
+	changeToTempDir(t)
+	writeTestFile(t, "src/main.txt", "hello")
+
+	var tests = []struct {
+		prepare               func()
+		input                 string
+		expectedHits          []string
+		expectedMisses        []string
+		expectedInvalidatedBy map[string]string
+		expectedSuffix        string
+	}{
+		// Nothing is cached yet
+		{func() {}, cachedRunInput, []string{}, []string{"build", "package"}, map[string]string{"package": "build"}, ""},
+		// Nothing changed, and deleted outputs are restored
+		{func() { os.RemoveAll("out") }, cachedRunInput, []string{"build", "package"}, []string{}, map[string]string{}, ""},
+		// A new input file changes build's key, which invalidates package
+		{func() { writeTestFile(t, "src/extra.txt", "world") }, cachedRunInput, []string{}, []string{"build", "package"}, map[string]string{"package": "build"}, ""},
+		// Only package's own environment changed
+		{func() {}, cachedRunChangedEnvInput, []string{"build"}, []string{"package"}, map[string]string{}, "!"},
+	}
+
+	for i, testCase := range tests {
+		testCase.prepare()
+
+		var output bytes.Buffer
+		report, execErr := ExecuteUserJob(context.Background(), testCase.input, &output, &output, WithCacheDir(".cache"))
+		if execErr != nil {
+			t.Fatalf("test %d: received unexpected error: %s", i, execErr.Error())
+		}
+
+		if !report.Succeeded() {
+			t.Fatalf("test %d: job should have succeeded, got:\n%s%s", i, output.String(), report.String())
+		}
+		if !areStringSlicesEqual(report.CacheHits(), testCase.expectedHits) {
+			t.Errorf("test %d: expected hits %v, got %v", i, testCase.expectedHits, report.CacheHits())
+		}
+		if !areStringSlicesEqual(report.CacheMisses(), testCase.expectedMisses) {
+			t.Errorf("test %d: expected misses %v, got %v", i, testCase.expectedMisses, report.CacheMisses())
+		}
+		for _, result := range report.Results {
+			if result.InvalidatedBy != testCase.expectedInvalidatedBy[result.StepId] {
+				t.Errorf("test %d: expected %s to be invalidated by %q, got %q", i, result.StepId, testCase.expectedInvalidatedBy[result.StepId], result.InvalidatedBy)
+			}
+		}
+
+		// Whether restored or rebuilt, the final output should reflect the current inputs
+		packaged, readErr := os.ReadFile("out/package.txt")
+		if readErr != nil {
+			t.Fatalf("test %d: could not read output: %s", i, readErr.Error())
+		}
+		built, _ := os.ReadFile("out/build.txt")
+		if string(packaged) != string(built)+testCase.expectedSuffix {
+			t.Errorf("test %d: stale output %q from build output %q", i, packaged, built)
+		}
+	}
+}
+
+// generate declares no inputs or outputs, so it runs every time, and package can't trust its
+// cached output afterwards even though its own key hasn't changed
+func TestUncachedStepsInvalidateTheirDependents(t *testing.T) {
+	changeToTempDir(t)
+
+	for run, source := range []string{"hello", "world"} {
+		writeTestFile(t, "src/main.txt", source)
+
+		var output bytes.Buffer
+		report, execErr := ExecuteUserJob(context.Background(), uncachedProducerInput, &output, &output, WithCacheDir(".cache"))
+		if execErr != nil {
+			t.Fatalf("run %d: received unexpected error: %s", run, execErr.Error())
+		}
+
+		if !areStringSlicesEqual(report.CacheHits(), []string{}) || !areStringSlicesEqual(report.CacheMisses(), []string{"package"}) {
+			t.Errorf("run %d: expected package to miss, got:\n%s", run, report.String())
+		}
+		for _, result := range report.Results {
+			if result.StepId == "package" && result.InvalidatedBy != "generate" {
+				t.Errorf("run %d: expected package to be invalidated by generate, got %q", run, result.InvalidatedBy)
+			}
+		}
+
+		if packaged, _ := os.ReadFile("out/package.txt"); string(packaged) != source {
+			t.Errorf("run %d: stale output %q, expected %q", run, packaged, source)
+		}
+	}
+}
+
+func TestFailedStepsAreNotCached(t *testing.T) {
+	changeToTempDir(t)
+
+	for run := 0; run < 2; run++ {
+		var output bytes.Buffer
+		report, execErr := ExecuteUserJob(context.Background(), failingCachedRunInput, &output, &output, WithCacheDir(".cache"))
+		if execErr != nil {
+			t.Fatalf("run %d: received unexpected error: %s", run, execErr.Error())
+		}
+
+		if !areStringSlicesEqual(report.CacheMisses(), []string{"build"}) || !areStringSlicesEqual(report.Failed(), []string{"build"}) {
+			t.Errorf("run %d: expected build to miss and fail, got:\n%s", run, report.String())
+		}
+	}
+}
+
+func TestOutputsMustStayInWorkingDirectory(t *testing.T) {
+	var tests = []struct {
+		path     string
+		expected bool
+	}{
+		{"out/build.txt", true},
+		{"out/../build.txt", true},
+		{"./out/*", true},
+		{"../build.txt", false},
+		{"out/../../build.txt", false},
+		{"/tmp/build.txt", false},
+		{"..", false},
+	}
+
+	for i, testCase := range tests {
+		if isLocalPath(testCase.path) != testCase.expected {
+			t.Errorf("test %d: expected %s local to be %t", i, testCase.path, testCase.expected)
+		}
+	}
+}
+
+// Runs the rest of the test from an empty directory, since inputs and outputs are relative paths
+func changeToTempDir(t *testing.T) {
+	originalDir, getwdErr := os.Getwd()
+	if getwdErr != nil {
+		t.Fatalf("could not get working directory: %s", getwdErr.Error())
+	}
+	if chdirErr := os.Chdir(t.TempDir()); chdirErr != nil {
+		t.Fatalf("could not change directory: %s", chdirErr.Error())
+	}
+	t.Cleanup(func() {
+		os.Chdir(originalDir)
+	})
+}
+
+func writeTestFile(t *testing.T, path string, contents string) {
+	if mkdirErr := os.MkdirAll(filepath.Dir(path), 0777); mkdirErr != nil {
+		t.Fatalf("could not create directory: %s", mkdirErr.Error())
+	}
+	if writeErr := os.WriteFile(path, []byte(contents), 0666); writeErr != nil {
+		t.Fatalf("could not write file: %s", writeErr.Error())
+	}
+}
+
+const cachedRunInput string = `
+- step: "build"
+  dependencies: []
+  precedence: 100
+  inputs: ["src/*.txt"]
+  outputs: ["out/build.txt"]
+  run:
+    command: sh
+    args: ["-c", "mkdir -p out && cat src/*.txt > out/build.txt"]
+- step: "package"
+  dependencies: ["build"]
+  precedence: 50
+  outputs: ["out/package.txt"]
+  run:
+    command: sh
+    args: ["-c", "cat out/build.txt > out/package.txt && printf \"$PACKAGE_SUFFIX\" >> out/package.txt"]
+    env:
+      PACKAGE_SUFFIX: ""
+`
+
+const cachedRunChangedEnvInput string = `
+- step: "build"
+  dependencies: []
+  precedence: 100
+  inputs: ["src/*.txt"]
+  outputs: ["out/build.txt"]
+  run:
+    command: sh
+    args: ["-c", "mkdir -p out && cat src/*.txt > out/build.txt"]
+- step: "package"
+  dependencies: ["build"]
+  precedence: 50
+  outputs: ["out/package.txt"]
+  run:
+    command: sh
+    args: ["-c", "cat out/build.txt > out/package.txt && printf \"$PACKAGE_SUFFIX\" >> out/package.txt"]
+    env:
+      PACKAGE_SUFFIX: "!"
+`
+
+const uncachedProducerInput string = `
+- step: "generate"
+  dependencies: []
+  precedence: 100
+  run:
+    command: sh
+    args: ["-c", "cp src/main.txt generated.txt"]
+- step: "package"
+  dependencies: ["generate"]
+  precedence: 50
+  outputs: ["out/package.txt"]
+  run:
+    command: sh
+    args: ["-c", "mkdir -p out && cp generated.txt out/package.txt"]
+`
+
+const failingCachedRunInput string = `
+- step: "build"
+  dependencies: []
+  precedence: 100
+  outputs: ["out/build.txt"]
+  run:
+    command: sh
+    args: ["-c", "mkdir -p out && echo partial > out/build.txt && exit 1"]
+`
@@ -0,0 +1,326 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// The kinds of change a JobChange can describe
+const (
+	ChangeStepAdded         = "step-added"
+	ChangeStepRemoved       = "step-removed"
+	ChangePrecedence        = "precedence-changed"
+	ChangeDependencyAdded   = "dependency-added"
+	ChangeDependencyRemoved = "dependency-removed"
+)
+
+// A single semantic change between two versions of a job. StepId is the step the change
+// belongs to; for dependency changes that's the dependent step, and DependencyId its parent.
+type JobChange struct {
+	Kind          string
+	StepId        string
+	DependencyId  string
+	OldPrecedence int64
+	NewPrecedence int64
+}
+
+func (change JobChange) String() string {
+	switch change.Kind {
+	case ChangeStepAdded:
+		return fmt.Sprintf("added step %s (precedence %d)", change.StepId, change.NewPrecedence)
+	case ChangeStepRemoved:
+		return fmt.Sprintf("removed step %s", change.StepId)
+	case ChangePrecedence:
+		return fmt.Sprintf("precedence of %s changed %d -> %d", change.StepId, change.OldPrecedence, change.NewPrecedence)
+	case ChangeDependencyAdded:
+		return fmt.Sprintf("%s now depends on %s", change.StepId, change.DependencyId)
+	case ChangeDependencyRemoved:
+		return fmt.Sprintf("%s no longer depends on %s", change.StepId, change.DependencyId)
+	}
+	return change.Kind
+}
+
+// A step that's in both versions of the job but runs at a different position. Causes holds
+// the changes that plausibly moved it.
+type MovedStep struct {
+	StepId      string
+	OldPosition int
+	NewPosition int
+	Causes      []JobChange
+}
+
+// The semantic difference between two versions of a job, plus both resulting orderings
+type JobDiff struct {
+	Changes     []JobChange
+	OldOrdering []string
+	NewOrdering []string
+	MovedSteps  []MovedStep
+}
+
+// Parses, validates and schedules both versions of the job and compares them
+func DiffUserJobs(oldYamlStr string, newYamlStr string, opts ...Option) (*JobDiff, error) {
+
+	ctx, cancel := NewProcessingContext(getOptions(opts).limits)
+	defer cancel()
+
+	oldJob, oldJobErr := getValidatedJob(oldYamlStr, opts...)
+	if oldJobErr != nil {
+		return nil, fmt.Errorf("old job: %w", oldJobErr)
+	}
+
+	newJob, newJobErr := getValidatedJob(newYamlStr, opts...)
+	if newJobErr != nil {
+		return nil, fmt.Errorf("new job: %w", newJobErr)
+	}
+
+	return diffSteps(ctx, oldJob.Steps, newJob.Steps)
+}
+
+func diffSteps(ctx context.Context, oldSteps []*Step, newSteps []*Step) (*JobDiff, error) {
+
+	oldStepsByIdMap := getStepsByIdMap(oldSteps)
+	newStepsByIdMap := getStepsByIdMap(newSteps)
+
+	jobDiff := &JobDiff{
+		Changes:    getJobChanges(oldStepsByIdMap, newStepsByIdMap),
+		MovedSteps: make([]MovedStep, 0),
+	}
+
+	var scheduleErr error
+	jobDiff.OldOrdering, scheduleErr = scheduleSteps(ctx, oldSteps)
+	if scheduleErr != nil {
+		return nil, fmt.Errorf("old job: %w", scheduleErr)
+	}
+
+	jobDiff.NewOrdering, scheduleErr = scheduleSteps(ctx, newSteps)
+	if scheduleErr != nil {
+		return nil, fmt.Errorf("new job: %w", scheduleErr)
+	}
+
+	oldPositions := getPositionsById(jobDiff.OldOrdering)
+	newPositions := getPositionsById(jobDiff.NewOrdering)
+	for _, stepId := range jobDiff.NewOrdering {
+		oldPosition, inOld := oldPositions[stepId]
+		if !inOld || oldPosition == newPositions[stepId] {
+			continue
+		}
+
+		jobDiff.MovedSteps = append(jobDiff.MovedSteps, MovedStep{
+			StepId:      stepId,
+			OldPosition: oldPosition,
+			NewPosition: newPositions[stepId],
+			Causes:      attributeMove(stepId, jobDiff.Changes, oldStepsByIdMap, newStepsByIdMap, oldPositions, newPositions),
+		})
+	}
+
+	return jobDiff, nil
+}
+
+// Lists added and removed steps, precedence changes and dependency edge changes, in that order.
+// Edges belonging to an added or removed step are folded into that step's change.
+func getJobChanges(oldStepsByIdMap map[string]*Step, newStepsByIdMap map[string]*Step) []JobChange {
+
+	changes := make([]JobChange, 0)
+
+	for _, stepId := range getSortedStepIds(newStepsByIdMap) {
+		if _, inOld := oldStepsByIdMap[stepId]; !inOld {
+			changes = append(changes, JobChange{Kind: ChangeStepAdded, StepId: stepId, NewPrecedence: newStepsByIdMap[stepId].Precedence})
+		}
+	}
+
+	for _, stepId := range getSortedStepIds(oldStepsByIdMap) {
+		if _, inNew := newStepsByIdMap[stepId]; !inNew {
+			changes = append(changes, JobChange{Kind: ChangeStepRemoved, StepId: stepId, OldPrecedence: oldStepsByIdMap[stepId].Precedence})
+		}
+	}
+
+	edgeChanges := make([]JobChange, 0)
+	for _, stepId := range getSortedStepIds(newStepsByIdMap) {
+		oldStep, inOld := oldStepsByIdMap[stepId]
+		if !inOld {
+			continue
+		}
+		newStep := newStepsByIdMap[stepId]
+
+		if oldStep.Precedence != newStep.Precedence {
+			changes = append(changes, JobChange{
+				Kind:          ChangePrecedence,
+				StepId:        stepId,
+				OldPrecedence: oldStep.Precedence,
+				NewPrecedence: newStep.Precedence,
+			})
+		}
+
+		oldDeps := getDependencySet(oldStep)
+		newDeps := getDependencySet(newStep)
+		for _, parentStepId := range getSortedKeys(newDeps) {
+			if !oldDeps[parentStepId] {
+				edgeChanges = append(edgeChanges, JobChange{Kind: ChangeDependencyAdded, StepId: stepId, DependencyId: parentStepId})
+			}
+		}
+		for _, parentStepId := range getSortedKeys(oldDeps) {
+			if !newDeps[parentStepId] {
+				edgeChanges = append(edgeChanges, JobChange{Kind: ChangeDependencyRemoved, StepId: stepId, DependencyId: parentStepId})
+			}
+		}
+	}
+
+	return append(changes, edgeChanges...)
+}
+
+// Works out which changes could have moved stepId. A step's position depends on its own
+// precedence and dependencies, those of everything upstream of it, and on the steps it
+// competes with; so we collect the step, its ancestors in either version, and every step whose
+// relative order with it flipped (plus their ancestors). Changes to any of those are causes, as
+// are added steps that now run before it and removed steps that used to.
+func attributeMove(
+	stepId string,
+	changes []JobChange,
+	oldStepsByIdMap map[string]*Step,
+	newStepsByIdMap map[string]*Step,
+	oldPositions map[string]int,
+	newPositions map[string]int,
+) []JobChange {
+
+	relevant := map[string]bool{stepId: true}
+	addWithAncestors := func(id string) {
+		relevant[id] = true
+		for ancestorId := range getTransitiveDependencies(oldStepsByIdMap, id) {
+			relevant[ancestorId] = true
+		}
+		for ancestorId := range getTransitiveDependencies(newStepsByIdMap, id) {
+			relevant[ancestorId] = true
+		}
+	}
+	addWithAncestors(stepId)
+
+	for otherId, otherOldPosition := range oldPositions {
+		otherNewPosition, inNew := newPositions[otherId]
+		if !inNew || otherId == stepId {
+			continue
+		}
+		wasBefore := otherOldPosition < oldPositions[stepId]
+		isBefore := otherNewPosition < newPositions[stepId]
+		if wasBefore != isBefore {
+			addWithAncestors(otherId)
+		}
+	}
+
+	causes := make([]JobChange, 0)
+	for _, change := range changes {
+		switch change.Kind {
+		case ChangeStepAdded:
+			if newPositions[change.StepId] < newPositions[stepId] {
+				causes = append(causes, change)
+			}
+		case ChangeStepRemoved:
+			if oldPositions[change.StepId] < oldPositions[stepId] {
+				causes = append(causes, change)
+			}
+		default:
+			if relevant[change.StepId] {
+				causes = append(causes, change)
+			}
+		}
+	}
+
+	return causes
+}
+
+// Renders the diff as text: the list of changes, then the two orderings side by side with
+// moved steps marked, then the attribution for each moved step
+func (jobDiff *JobDiff) String() string {
+
+	var builder strings.Builder
+
+	if len(jobDiff.Changes) == 0 {
+		builder.WriteString("no changes to steps, precedences or dependencies\n")
+	} else {
+		builder.WriteString("changes:\n")
+		for _, change := range jobDiff.Changes {
+			builder.WriteString(fmt.Sprintf("  %s %s\n", getChangeMarker(change), change.String()))
+		}
+	}
+
+	movedById := make(map[string]MovedStep, len(jobDiff.MovedSteps))
+	for _, moved := range jobDiff.MovedSteps {
+		movedById[moved.StepId] = moved
+	}
+
+	oldWidth := len("old")
+	for _, stepId := range jobDiff.OldOrdering {
+		if len(stepId) > oldWidth {
+			oldWidth = len(stepId)
+		}
+	}
+
+	rowCount := len(jobDiff.OldOrdering)
+	if len(jobDiff.NewOrdering) > rowCount {
+		rowCount = len(jobDiff.NewOrdering)
+	}
+
+	builder.WriteString("\nordering:\n")
+	builder.WriteString(fmt.Sprintf("  %4s  %-*s    %s\n", "#", oldWidth, "old", "new"))
+	for i := 0; i < rowCount; i++ {
+		oldId, newId := "", ""
+		if i < len(jobDiff.OldOrdering) {
+			oldId = jobDiff.OldOrdering[i]
+		}
+
+		marker := " "
+		if i < len(jobDiff.NewOrdering) {
+			newId = jobDiff.NewOrdering[i]
+			if moved, isMoved := movedById[newId]; isMoved {
+				marker = "*"
+				newId = fmt.Sprintf("%s (was %d)", newId, moved.OldPosition)
+			} else if !containsString(jobDiff.OldOrdering, newId) {
+				marker = "+"
+			}
+		}
+
+		builder.WriteString(fmt.Sprintf("  %4d  %-*s  %s %s\n", i+1, oldWidth, oldId, marker, newId))
+	}
+
+	if len(jobDiff.MovedSteps) > 0 {
+		builder.WriteString("\nmoved steps:\n")
+		for _, moved := range jobDiff.MovedSteps {
+			builder.WriteString(fmt.Sprintf("  %s: %d -> %d\n", moved.StepId, moved.OldPosition, moved.NewPosition))
+			for _, cause := range moved.Causes {
+				builder.WriteString(fmt.Sprintf("    because %s\n", cause.String()))
+			}
+		}
+	}
+
+	return builder.String()
+}
+
+func getChangeMarker(change JobChange) string {
+	switch change.Kind {
+	case ChangeStepAdded, ChangeDependencyAdded:
+		return "+"
+	case ChangeStepRemoved, ChangeDependencyRemoved:
+		return "-"
+	}
+	return "~"
+}
+
+// Returns a 1-based position for each step ID in an ordering
+func getPositionsById(ordering []string) map[string]int {
+
+	positions := make(map[string]int, len(ordering))
+	for i, stepId := range ordering {
+		positions[stepId] = i + 1
+	}
+
+	return positions
+}
+
+func containsString(haystack []string, needle string) bool {
+	for _, value := range haystack {
+		if value == needle {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package scheduler
+
+import (
+	"testing"
+)
+
+func TestCorrectlyDiffsJobs(t *testing.T) {
+	var tests = []struct {
+		oldYamlInput  string
+		newYamlInput  string
+		changeKinds   []string
+		movedStepIds  []string
+		firstCauseIds []string
+	}{
+		{basicWithDependenciesInput, basicWithDependenciesInput, []string{}, []string{}, []string{}},
+		{complexWithDependenciesInput, complexWithDependenciesMultipleStepLeadingTrailingWhitespaceInput, []string{}, []string{}, []string{}},
+		{
+			oneStepInput,
+			basicWithDependenciesInput,
+			[]string{ChangeStepAdded, ChangeStepAdded, ChangeStepAdded, ChangeStepAdded, ChangePrecedence},
+			[]string{},
+			[]string{},
+		},
+		{
+			basicWithDependenciesInput,
+			basicWithDependenciesRetunedInput,
+			[]string{ChangePrecedence, ChangeDependencyAdded, ChangeDependencyRemoved},
+			[]string{"create user 2", "create user 1"},
+			[]string{"create user 2", "create user 2"},
+		},
+	}
+
+	for i, testCase := range tests {
+		jobDiff, diffErr := DiffUserJobs(testCase.oldYamlInput, testCase.newYamlInput)
+		if diffErr != nil {
+			t.Errorf("test %d: expected success, got error: %s", i, diffErr.Error())
+			continue
+		}
+
+		changeKinds := make([]string, len(jobDiff.Changes))
+		for j, change := range jobDiff.Changes {
+			changeKinds[j] = change.Kind
+		}
+		if !areStringSlicesEqual(changeKinds, testCase.changeKinds) {
+			t.Errorf("test %d: did not get equal change kinds, got: %v", i, changeKinds)
+		}
+
+		movedStepIds := make([]string, len(jobDiff.MovedSteps))
+		firstCauseIds := make([]string, len(jobDiff.MovedSteps))
+		for j, moved := range jobDiff.MovedSteps {
+			movedStepIds[j] = moved.StepId
+			if len(moved.Causes) > 0 {
+				firstCauseIds[j] = moved.Causes[0].StepId
+			}
+		}
+		if !areStringSlicesEqual(movedStepIds, testCase.movedStepIds) {
+			t.Errorf("test %d: did not get equal moved steps, got: %v", i, movedStepIds)
+		}
+		if !areStringSlicesEqual(firstCauseIds, testCase.firstCauseIds) {
+			t.Errorf("test %d: did not get equal causes, got: %v", i, firstCauseIds)
+		}
+	}
+}
+
+func TestDiffRejectsInvalidJobs(t *testing.T) {
+	if _, diffErr := DiffUserJobs(circularDependenciesInput, basicWithDependenciesInput); diffErr == nil {
+		t.Errorf("should have received error for old job, did not")
+	}
+
+	if _, diffErr := DiffUserJobs(basicWithDependenciesInput, nonYamlStringInput); diffErr == nil {
+		t.Errorf("should have received error for new job, did not")
+	}
+}
+
+// basicWithDependenciesInput with create user 2 bumped above create user 1,
+// and create user 3 depending on create user 1 rather than create user 4
+const basicWithDependenciesRetunedInput string = `
+- step: "create user 1"
+  dependencies: ["prepare database"]
+  precedence: 100
+- step: "create user 2"
+  dependencies: ["prepare database"]
+  precedence: 150
+- step: "prepare database"
+  dependencies: []
+  precedence: 10
+- step: "create user 3"
+  dependencies: ["create user 1"]
+  precedence: 10
+- step: "create user 4"
+  dependencies: ["create user 2"]
+  precedence: 100
+`
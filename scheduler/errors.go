@@ -0,0 +1,51 @@
+package scheduler
+
+import (
+	"strings"
+)
+
+// Returned when the input isn't YAML, or can't be decoded as a list of steps
+type ParseError struct {
+	Err error
+}
+
+func (parseErr *ParseError) Error() string {
+	return "invalid yaml: " + parseErr.Err.Error()
+}
+
+func (parseErr *ParseError) Unwrap() error {
+	return parseErr.Err
+}
+
+// Returned when the input exceeds one of the ResourceLimits. Limit names the cap that was hit,
+// using the same name as its command-line flag (e.g. "max-steps").
+type LimitError struct {
+	Limit   string
+	Message string
+}
+
+func (limitErr *LimitError) Error() string {
+	return limitErr.Message
+}
+
+// Returned when the steps break one of the job rules: a missing or malformed step ID or
+// precedence, a duplicate or confusable ID, an unknown dependency, or no steps at all.
+// StepIds holds the steps the problem was found on, where they're known.
+type ValidationError struct {
+	StepIds []string
+	Message string
+}
+
+func (validationErr *ValidationError) Error() string {
+	return validationErr.Message
+}
+
+// Returned when no ordering exists because of a circular dependency. StepIds holds every step
+// that couldn't be scheduled, which is the steps in the cycle plus everything downstream of it.
+type CycleError struct {
+	StepIds []string
+}
+
+func (cycleErr *CycleError) Error() string {
+	return "possible circular dependency detected, could not schedule: " + strings.Join(cycleErr.StepIds, ", ")
+}
@@ -0,0 +1,535 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The longest wait between retries, however many there have been
+const maxRetryBackoff = 5 * time.Minute
+
+// How long to keep reading a command's output once it has exited or been killed. A process it
+// started outside its process group can hold the output open indefinitely, which would otherwise
+// keep the step from ever finishing.
+const commandWaitDelay = 2 * time.Second
+
+// The outcome of executing one step. ExitCode and Err are from the last attempt, and Duration
+// covers every attempt including the waits between them.
+type StepResult struct {
+	StepId   string
+	State    StepState
+	ExitCode int // -1 if the command couldn't be started or didn't exit on its own
+	Duration time.Duration
+	Err      error // Why the step failed, nil if it succeeded
+	Attempts []StepAttempt
+
+	Cache         CacheStatus // Empty if the step wasn't cacheable or caching was off
+	InvalidatedBy string      // The upstream step whose cache miss forced this one to run
+}
+
+// One run of a step's command
+type StepAttempt struct {
+	ExitCode int // -1 if the command couldn't be started or didn't exit on its own
+	Duration time.Duration
+	Err      error // Why the attempt failed, nil if it succeeded
+	TimedOut bool  // Whether the attempt was killed for running past the step's timeout
+}
+
+func (attempt StepAttempt) String() string {
+	if attempt.Err != nil {
+		return fmt.Sprintf("exit %d after %s: %s", attempt.ExitCode, attempt.Duration.Round(time.Millisecond), attempt.Err.Error())
+	}
+	return fmt.Sprintf("exit %d after %s", attempt.ExitCode, attempt.Duration.Round(time.Millisecond))
+}
+
+func (result StepResult) String() string {
+	if result.Err != nil {
+		return fmt.Sprintf("%s: %s (exit %d) after %s: %s", result.StepId, result.State, result.ExitCode, result.Duration.Round(time.Millisecond), result.Err.Error())
+	}
+	return fmt.Sprintf("%s: %s (exit %d) after %s", result.StepId, result.State, result.ExitCode, result.Duration.Round(time.Millisecond))
+}
+
+// The outcome of executing a job. Results are in the order the steps ran. Skipped holds the
+// steps downstream of a failure, and NotRun the steps that never started because execution
+// stopped at the first failure.
+type ExecutionReport struct {
+	Results []StepResult
+	Skipped []string
+	NotRun  []string
+}
+
+// Reports whether every step ran and succeeded
+func (report *ExecutionReport) Succeeded() bool {
+	return len(report.Failed()) == 0 && len(report.Skipped) == 0 && len(report.NotRun) == 0
+}
+
+// Returns the IDs of the steps that failed, in the order they ran
+func (report *ExecutionReport) Failed() []string {
+	return report.getStepIdsWithState(StepFailed)
+}
+
+// Returns the IDs of the steps that succeeded, in the order they ran
+func (report *ExecutionReport) Completed() []string {
+	return report.getStepIdsWithState(StepSucceeded)
+}
+
+// Returns the IDs of the steps whose outputs were restored from the cache
+func (report *ExecutionReport) CacheHits() []string {
+	return report.getStepIdsWithCacheStatus(CacheHit)
+}
+
+// Returns the IDs of the cacheable steps that had to run
+func (report *ExecutionReport) CacheMisses() []string {
+	return report.getStepIdsWithCacheStatus(CacheMiss)
+}
+
+func (report *ExecutionReport) getStepIdsWithCacheStatus(status CacheStatus) []string {
+	stepIds := make([]string, 0)
+	for _, result := range report.Results {
+		if result.Cache == status {
+			stepIds = append(stepIds, result.StepId)
+		}
+	}
+	return stepIds
+}
+
+func (report *ExecutionReport) getStepIdsWithState(state StepState) []string {
+	stepIds := make([]string, 0)
+	for _, result := range report.Results {
+		if result.State == state {
+			stepIds = append(stepIds, result.StepId)
+		}
+	}
+	return stepIds
+}
+
+// A summary table with each step's status, exit code and duration, followed by the steps
+// grouped by outcome
+func (report *ExecutionReport) String() string {
+
+	var builder strings.Builder
+
+	idWidth := len("step")
+	for _, result := range report.Results {
+		if len(result.StepId) > idWidth {
+			idWidth = len(result.StepId)
+		}
+	}
+
+	builder.WriteString(fmt.Sprintf("  %-*s  %-9s  %4s  %8s  %-5s  %s\n", idWidth, "step", "status", "exit", "attempts", "cache", "duration"))
+	for _, result := range report.Results {
+		cache := string(result.Cache)
+		if cache == "" {
+			cache = "-"
+		}
+		builder.WriteString(fmt.Sprintf("  %-*s  %-9s  %4d  %8d  %-5s  %s\n", idWidth, result.StepId, result.State, result.ExitCode, len(result.Attempts), cache, result.Duration.Round(time.Millisecond)))
+	}
+
+	// Every attempt is listed for steps that failed or needed a retry
+	for _, result := range report.Results {
+		if result.Err == nil && len(result.Attempts) <= 1 {
+			continue
+		}
+
+		builder.WriteString(fmt.Sprintf("\n%s %s:\n", result.StepId, result.State))
+		for i, attempt := range result.Attempts {
+			builder.WriteString(fmt.Sprintf("  attempt %d: %s\n", i+1, attempt.String()))
+		}
+	}
+
+	builder.WriteString("\n")
+	for _, group := range []struct {
+		label   string
+		stepIds []string
+	}{
+		{"succeeded", report.Completed()},
+		{"failed", report.Failed()},
+		{"skipped", report.Skipped},
+		{"not run", report.NotRun},
+		{"cache hits", report.CacheHits()},
+		{"cache misses", report.CacheMisses()},
+	} {
+		if len(group.stepIds) > 0 {
+			builder.WriteString(fmt.Sprintf("%s (%d): %s\n", group.label, len(group.stepIds), strings.Join(group.stepIds, ", ")))
+		}
+	}
+
+	return builder.String()
+}
+
+// Parses, validates and executes the user's job. See ExecuteJob.
+func ExecuteUserJob(ctx context.Context, yamlStr string, stdout io.Writer, stderr io.Writer, opts ...Option) (*ExecutionReport, error) {
+
+	job, jobErr := getValidatedJob(yamlStr, opts...)
+	if jobErr != nil {
+		return nil, jobErr
+	}
+
+	return ExecuteJob(ctx, job, stdout, stderr, opts...)
+}
+
+// Runs the job's steps one at a time in scheduled order. A failed step's dependents are skipped,
+// and execution stops at the first failure unless WithKeepGoing is given. Each command's output
+// is streamed to stdout and stderr with every line prefixed by its step ID. Cancelling ctx kills
+// the running command. Step failures are reported in the ExecutionReport; the error is only for
+// a job that can't be scheduled at all.
+func ExecuteJob(ctx context.Context, job *Job, stdout io.Writer, stderr io.Writer, opts ...Option) (*ExecutionReport, error) {
+
+	resolvedOpts := getOptions(opts)
+
+	var cache *stepCache
+	if resolvedOpts.cacheDir != "" {
+		cache = &stepCache{dir: resolvedOpts.cacheDir, keysById: make(map[string]string), invalidatedBy: make(map[string]string)}
+	}
+
+	// Only checking the job is held to the processing timeout; the steps may run for as long as
+	// they need
+	schedulingCtx, cancel := NewProcessingContext(resolvedOpts.limits)
+	scheduler, schedulerErr := NewScheduler(schedulingCtx, job)
+	cancel()
+	if schedulerErr != nil {
+		return nil, schedulerErr
+	}
+
+	lockedStdout, lockedStderr := getLockedWriters(stdout, stderr)
+
+	report := &ExecutionReport{}
+	for {
+		readyStepIds := scheduler.Ready()
+		if len(readyStepIds) == 0 {
+			break
+		}
+
+		stepId := readyStepIds[0]
+		if startErr := scheduler.Start(stepId); startErr != nil {
+			return nil, startErr
+		}
+
+		var result StepResult
+		if cache != nil {
+			result = executeCachedStep(ctx, job.GetStep(stepId), cache, lockedStdout, lockedStderr)
+		} else {
+			result = executeStep(ctx, job.GetStep(stepId), lockedStdout, lockedStderr)
+		}
+		report.Results = append(report.Results, result)
+
+		if result.State == StepSucceeded {
+			if completeErr := scheduler.Complete(stepId); completeErr != nil {
+				return nil, completeErr
+			}
+			continue
+		}
+
+		skipped, failErr := scheduler.Fail(stepId)
+		if failErr != nil {
+			return nil, failErr
+		}
+		report.Skipped = append(report.Skipped, skipped...)
+
+		if !resolvedOpts.keepGoing || ctx.Err() != nil {
+			break
+		}
+	}
+
+	for _, step := range job.Steps {
+		if state, _ := scheduler.State(step.StepId); state == StepPending {
+			report.NotRun = append(report.NotRun, step.StepId)
+		}
+	}
+
+	return report, nil
+}
+
+// Restores the step's outputs from the cache if nothing that went into them has changed and
+// no dependency missed, and otherwise runs it and caches the outputs on success
+func executeCachedStep(ctx context.Context, step *Step, cache *stepCache, stdout io.Writer, stderr io.Writer) StepResult {
+
+	prefix := getAttemptPrefix(step.StepId, 1)
+
+	key, keyErr := getStepCacheKey(step, cache.keysById)
+	if keyErr != nil {
+		return StepResult{StepId: step.StepId, State: StepFailed, ExitCode: -1, Err: fmt.Errorf("could not hash inputs: %w", keyErr)}
+	}
+	cache.keysById[step.StepId] = key
+
+	// A miss anywhere upstream means this step's inputs may have been regenerated
+	invalidatedBy := ""
+	for _, dependencyId := range step.DependencyIds {
+		if cause, isInvalidated := cache.invalidatedBy[dependencyId]; isInvalidated {
+			invalidatedBy = cause
+			break
+		}
+	}
+
+	if !isCacheable(step) {
+		// A command we can't see the effects of may have regenerated whatever its dependents read
+		if step.Run != nil {
+			cache.invalidatedBy[step.StepId] = step.StepId
+		} else if invalidatedBy != "" {
+			cache.invalidatedBy[step.StepId] = invalidatedBy
+		}
+		return executeStep(ctx, step, stdout, stderr)
+	}
+
+	if invalidatedBy == "" {
+		if manifest, isHit := cache.lookup(key); isHit {
+			restoreErr := cache.restore(key, manifest)
+			if restoreErr == nil {
+				fmt.Fprintf(stderr, "%scache hit, restored %d output file(s)\n", prefix, len(manifest.Files))
+				return StepResult{StepId: step.StepId, State: StepSucceeded, Cache: CacheHit}
+			}
+			fmt.Fprintf(stderr, "%scould not restore from cache, running instead: %s\n", prefix, restoreErr.Error())
+		}
+		fmt.Fprintf(stderr, "%scache miss\n", prefix)
+	} else {
+		fmt.Fprintf(stderr, "%scache miss, invalidated by %s\n", prefix, invalidatedBy)
+	}
+
+	cache.invalidatedBy[step.StepId] = step.StepId
+	result := executeStep(ctx, step, stdout, stderr)
+	result.Cache, result.InvalidatedBy = CacheMiss, invalidatedBy
+
+	if result.State == StepSucceeded {
+		if storeErr := cache.store(key, step); storeErr != nil {
+			fmt.Fprintf(stderr, "%snot cached: %s\n", prefix, storeErr.Error())
+		}
+	}
+
+	return result
+}
+
+// Runs a single step on its own, outside of a job, e.g. on a worker that was handed the step by a
+// coordinator. Output and retries are handled as in ExecuteJob; caching is not.
+func ExecuteStep(ctx context.Context, step *Step, stdout io.Writer, stderr io.Writer) StepResult {
+	lockedStdout, lockedStderr := getLockedWriters(stdout, stderr)
+	return executeStep(ctx, step, lockedStdout, lockedStderr)
+}
+
+// Runs a single step's command until it succeeds or its retries run out, waiting out the
+// backoff between attempts. Steps without a command succeed straight away.
+func executeStep(ctx context.Context, step *Step, stdout io.Writer, stderr io.Writer) StepResult {
+
+	result := StepResult{StepId: step.StepId, State: StepSucceeded}
+	if step.Run == nil {
+		return result
+	}
+
+	startTime := time.Now()
+	backoff := step.RetryBackoff
+	for attemptNumber := 1; ; attemptNumber++ {
+		attempt := executeAttempt(ctx, step, attemptNumber, stdout, stderr)
+		result.Attempts = append(result.Attempts, attempt)
+		result.ExitCode, result.Err = attempt.ExitCode, attempt.Err
+
+		if attempt.Err == nil || attemptNumber > step.Retries || ctx.Err() != nil {
+			break
+		}
+
+		prefix := getAttemptPrefix(step.StepId, attemptNumber)
+		fmt.Fprintf(stderr, "%sattempt %d failed: %s, retrying in %s\n", prefix, attemptNumber, attempt.Err.Error(), backoff)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+		}
+		backoff = getNextRetryBackoff(backoff)
+	}
+	result.Duration = time.Since(startTime)
+
+	if result.Err != nil {
+		result.State = StepFailed
+	}
+
+	return result
+}
+
+// Doubles the wait before the next retry, up to maxRetryBackoff. A longer backoff set by the
+// step itself is kept as it is.
+func getNextRetryBackoff(backoff time.Duration) time.Duration {
+	if backoff >= maxRetryBackoff {
+		return backoff
+	}
+	return minDuration(backoff*2, maxRetryBackoff)
+}
+
+func minDuration(a time.Duration, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Runs the step's command once. The command gets its own process group so that on timeout or
+// cancellation anything it started is killed along with it.
+func executeAttempt(ctx context.Context, step *Step, attemptNumber int, stdout io.Writer, stderr io.Writer) StepAttempt {
+
+	attempt := StepAttempt{ExitCode: -1}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		attempt.Err = ctxErr
+		return attempt
+	}
+
+	attemptCtx := ctx
+	if step.Timeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, step.Timeout)
+		defer cancel()
+	}
+
+	prefix := getAttemptPrefix(step.StepId, attemptNumber)
+	stepStdout := &prefixWriter{out: stdout, prefix: prefix}
+	stepStderr := &prefixWriter{out: stderr, prefix: prefix}
+
+	command := exec.Command(step.Run.Command, step.Run.Args...)
+	command.Env = getStepEnvironment(step.Run)
+	command.Stdout = stepStdout
+	command.Stderr = stepStderr
+	command.WaitDelay = commandWaitDelay
+	setProcessGroup(command)
+
+	startTime := time.Now()
+	if startErr := command.Start(); startErr != nil {
+		attempt.Err = startErr
+		return attempt
+	}
+
+	waitDone := make(chan error, 1)
+	go func() {
+		waitDone <- command.Wait()
+	}()
+
+	var runErr error
+	select {
+	case runErr = <-waitDone:
+	case <-attemptCtx.Done():
+		killProcessGroup(command)
+		runErr = <-waitDone
+	}
+	attempt.Duration = time.Since(startTime)
+
+	stepStdout.Flush()
+	stepStderr.Flush()
+
+	// The command itself succeeded, and only something it left running is still holding its output
+	if errors.Is(runErr, exec.ErrWaitDelay) {
+		fmt.Fprintf(stderr, "%sstopped reading output %s after the command exited, something it started still has it open\n", prefix, commandWaitDelay)
+		runErr = nil
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(runErr, &exitErr) {
+		attempt.ExitCode = exitErr.ExitCode()
+	} else if runErr == nil {
+		attempt.ExitCode = 0
+	}
+
+	// A kill shows up as an exit error, so report why it was killed instead
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		attempt.Err = ctxErr
+	} else if attemptCtx.Err() != nil {
+		attempt.Err, attempt.TimedOut = fmt.Errorf("timed out after %s", step.Timeout), true
+	} else {
+		attempt.Err = runErr
+	}
+
+	return attempt
+}
+
+// Log lines from retries are marked with the attempt number so they can be told apart
+func getAttemptPrefix(stepId string, attemptNumber int) string {
+	if attemptNumber == 1 {
+		return "[" + stepId + "] "
+	}
+	return fmt.Sprintf("[%s #%d] ", stepId, attemptNumber)
+}
+
+// The executor's environment plus the step's own variables, which win on conflicts
+func getStepEnvironment(run *StepRun) []string {
+	return append(os.Environ(), getSortedEnv(run.Env)...)
+}
+
+// Returns the variables as KEY=value strings, sorted by key
+func getSortedEnv(env map[string]string) []string {
+
+	envKeys := make([]string, 0, len(env))
+	for envKey := range env {
+		envKeys = append(envKeys, envKey)
+	}
+	sort.Strings(envKeys)
+
+	envVars := make([]string, len(envKeys))
+	for i, envKey := range envKeys {
+		envVars[i] = envKey + "=" + env[envKey]
+	}
+
+	return envVars
+}
+
+// A command's stdout and stderr are copied on separate goroutines and may share a destination,
+// so writes to each are serialized, through the same lock if they're the same writer
+func getLockedWriters(stdout io.Writer, stderr io.Writer) (io.Writer, io.Writer) {
+	lockedStdout := &lockedWriter{out: stdout}
+	if stderr == stdout {
+		return lockedStdout, lockedStdout
+	}
+	return lockedStdout, &lockedWriter{out: stderr}
+}
+
+// Serializes writes from several sources to one destination
+type lockedWriter struct {
+	mutex sync.Mutex
+	out   io.Writer
+}
+
+func (writer *lockedWriter) Write(p []byte) (int, error) {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	return writer.out.Write(p)
+}
+
+// Writes each complete line with a prefix, holding back any partial line until it's finished
+// or Flush is called
+type prefixWriter struct {
+	out     io.Writer
+	prefix  string
+	pending []byte
+}
+
+func (writer *prefixWriter) Write(p []byte) (int, error) {
+
+	writer.pending = append(writer.pending, p...)
+	for {
+		newlineIndex := bytes.IndexByte(writer.pending, '\n')
+		if newlineIndex < 0 {
+			break
+		}
+
+		line := writer.pending[:newlineIndex+1]
+		if _, writeErr := writer.out.Write(append([]byte(writer.prefix), line...)); writeErr != nil {
+			return 0, writeErr
+		}
+		writer.pending = writer.pending[newlineIndex+1:]
+	}
+
+	return len(p), nil
+}
+
+// Writes out any partial final line, terminating it with a newline
+func (writer *prefixWriter) Flush() {
+	if len(writer.pending) == 0 {
+		return
+	}
+
+	writer.out.Write(append([]byte(writer.prefix), append(writer.pending, '\n')...))
+	writer.pending = nil
+}
@@ -0,0 +1,361 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestExecutesStepsInScheduledOrder(t *testing.T) {
+	var output bytes.Buffer
+	report, execErr := ExecuteUserJob(context.Background(), successfulRunInput, &output, &output)
+	if execErr != nil {
+		t.Fatalf("received unexpected error: %s", execErr.Error())
+	}
+
+	if !report.Succeeded() {
+		t.Errorf("job should have succeeded, got:\n%s", report.String())
+	}
+
+	ranStepIds := make([]string, len(report.Results))
+	for i, result := range report.Results {
+		ranStepIds[i] = result.StepId
+	}
+	if !areStringSlicesEqual(ranStepIds, successfulRunOutput) {
+		t.Errorf("steps ran out of order, got %v", ranStepIds)
+	}
+
+	expectedLogs := "[prepare database] preparing users\n[create user 1] creating 1\n[create user 1] partial\n"
+	if output.String() != expectedLogs {
+		t.Errorf("logs did not match, got:\n%s", output.String())
+	}
+}
+
+func TestExecutionStopsAtFirstFailure(t *testing.T) {
+	var output bytes.Buffer
+	report, execErr := ExecuteUserJob(context.Background(), failingRunInput, &output, &output)
+	if execErr != nil {
+		t.Fatalf("received unexpected error: %s", execErr.Error())
+	}
+
+	if report.Succeeded() {
+		t.Errorf("job should not have succeeded")
+	}
+
+	if len(report.Results) != 2 {
+		t.Fatalf("expected 2 results, got:\n%s", report.String())
+	}
+	failed := report.Results[1]
+	if failed.StepId != "create user 1" || failed.State != StepFailed || failed.ExitCode != 3 {
+		t.Errorf("unexpected failed step result: %s", failed.String())
+	}
+
+	if !areStringSlicesEqual(report.NotRun, []string{"create user 2"}) {
+		t.Errorf("expected create user 2 not to run, got %v", report.NotRun)
+	}
+	if len(report.Skipped) != 0 {
+		t.Errorf("nothing depends on the failed step, but got skipped %v", report.Skipped)
+	}
+}
+
+func TestKeepGoingSkipsOnlyDependentsOfFailures(t *testing.T) {
+	var tests = []struct {
+		opts              []Option
+		expectedCompleted []string
+		expectedFailed    []string
+		expectedSkipped   []string
+		expectedNotRun    []string
+	}{
+		{nil, []string{}, []string{"build web"}, []string{"deploy web", "smoke test"}, []string{"build api", "deploy api"}},
+		{[]Option{WithKeepGoing()}, []string{"build api", "deploy api"}, []string{"build web"}, []string{"deploy web", "smoke test"}, []string{}},
+	}
+
+	for i, testCase := range tests {
+		var output bytes.Buffer
+		report, execErr := ExecuteUserJob(context.Background(), branchingRunInput, &output, &output, testCase.opts...)
+		if execErr != nil {
+			t.Fatalf("test %d: received unexpected error: %s", i, execErr.Error())
+		}
+
+		if report.Succeeded() {
+			t.Errorf("test %d: job should not have succeeded", i)
+		}
+		if !areStringSlicesEqual(report.Completed(), testCase.expectedCompleted) {
+			t.Errorf("test %d: expected succeeded %v, got %v", i, testCase.expectedCompleted, report.Completed())
+		}
+		if !areStringSlicesEqual(report.Failed(), testCase.expectedFailed) {
+			t.Errorf("test %d: expected failed %v, got %v", i, testCase.expectedFailed, report.Failed())
+		}
+		if !areStringSlicesEqual(report.Skipped, testCase.expectedSkipped) {
+			t.Errorf("test %d: expected skipped %v, got %v", i, testCase.expectedSkipped, report.Skipped)
+		}
+		if !areStringSlicesEqual(report.NotRun, testCase.expectedNotRun) {
+			t.Errorf("test %d: expected not run %v, got %v", i, testCase.expectedNotRun, report.NotRun)
+		}
+	}
+}
+
+func TestRetriesRecordEveryAttempt(t *testing.T) {
+	var tests = []struct {
+		succeedOnAttempt  int
+		expectedState     StepState
+		expectedAttempts  int
+		expectedCompleted []string
+		expectedSkipped   []string
+	}{
+		{1, StepSucceeded, 1, []string{"flaky step", "after flaky step"}, []string{}},
+		{3, StepSucceeded, 3, []string{"flaky step", "after flaky step"}, []string{}},
+		{4, StepFailed, 3, []string{}, []string{"after flaky step"}},
+	}
+
+	for i, testCase := range tests {
+		// The command counts its runs in this file and fails until it reaches SUCCEED_ON
+		t.Setenv("COUNT_FILE", filepath.Join(t.TempDir(), "count"))
+		t.Setenv("SUCCEED_ON", fmt.Sprint(testCase.succeedOnAttempt))
+
+		var output bytes.Buffer
+		report, execErr := ExecuteUserJob(context.Background(), flakyRunInput, &output, &output)
+		if execErr != nil {
+			t.Fatalf("test %d: received unexpected error: %s", i, execErr.Error())
+		}
+
+		flaky := report.Results[0]
+		if flaky.State != testCase.expectedState || len(flaky.Attempts) != testCase.expectedAttempts {
+			t.Errorf("test %d: expected %s after %d attempt(s), got %s after %d", i, testCase.expectedState, testCase.expectedAttempts, flaky.State, len(flaky.Attempts))
+		}
+		for attemptIndex, attempt := range flaky.Attempts[:len(flaky.Attempts)-1] {
+			if attempt.ExitCode != 1 || attempt.Err == nil {
+				t.Errorf("test %d: expected attempt %d to fail, got %s", i, attemptIndex+1, attempt.String())
+			}
+		}
+
+		if !areStringSlicesEqual(report.Completed(), testCase.expectedCompleted) {
+			t.Errorf("test %d: expected succeeded %v, got %v", i, testCase.expectedCompleted, report.Completed())
+		}
+		if !areStringSlicesEqual(report.Skipped, testCase.expectedSkipped) {
+			t.Errorf("test %d: expected skipped %v, got %v", i, testCase.expectedSkipped, report.Skipped)
+		}
+	}
+}
+
+func TestTimeoutKillsProcessGroup(t *testing.T) {
+	var output bytes.Buffer
+	startTime := time.Now()
+	report, execErr := ExecuteUserJob(context.Background(), timeoutRunInput, &output, &output)
+	if execErr != nil {
+		t.Fatalf("received unexpected error: %s", execErr.Error())
+	}
+
+	// The backgrounded sleep keeps the output pipe open, so this only returns quickly if it was killed too
+	if elapsed := time.Since(startTime); elapsed > 5*time.Second {
+		t.Errorf("timed out step took %s to stop", elapsed)
+	}
+
+	slow := report.Results[0]
+	if slow.State != StepFailed || len(slow.Attempts) != 2 {
+		t.Fatalf("expected a failure after 2 attempts, got:\n%s", report.String())
+	}
+	for attemptIndex, attempt := range slow.Attempts {
+		if !attempt.TimedOut {
+			t.Errorf("expected attempt %d to time out, got %s", attemptIndex+1, attempt.String())
+		}
+	}
+}
+
+// setsid moves the sleep out of the step's process group, so it survives the step and keeps its
+// output open
+func TestLeftoverProcessesDontBlockSteps(t *testing.T) {
+	if _, lookErr := exec.LookPath("setsid"); lookErr != nil {
+		t.Skipf("setsid isn't available: %s", lookErr.Error())
+	}
+
+	var output bytes.Buffer
+	startTime := time.Now()
+	report, execErr := ExecuteUserJob(context.Background(), leftoverProcessRunInput, &output, &output)
+	if execErr != nil {
+		t.Fatalf("received unexpected error: %s", execErr.Error())
+	}
+
+	if elapsed := time.Since(startTime); elapsed > commandWaitDelay+2*time.Second {
+		t.Errorf("step with a leftover process took %s to finish", elapsed)
+	}
+	if !report.Succeeded() {
+		t.Errorf("expected the step to succeed, got:\n%s%s", output.String(), report.String())
+	}
+}
+
+func TestRetryBackoffIsCapped(t *testing.T) {
+	var tests = []struct {
+		backoff  time.Duration
+		expected time.Duration
+	}{
+		{time.Second, 2 * time.Second},
+		{3 * time.Minute, maxRetryBackoff},
+		{maxRetryBackoff, maxRetryBackoff},
+		{time.Hour, time.Hour}, // Set by the step, so it's kept
+	}
+
+	for i, testCase := range tests {
+		if backoff := getNextRetryBackoff(testCase.backoff); backoff != testCase.expected {
+			t.Errorf("test %d: expected %s, got %s", i, testCase.expected, backoff)
+		}
+	}
+
+	// However many retries there are, the wait never overflows
+	backoff := time.Second
+	for retry := 0; retry < 100; retry++ {
+		backoff = getNextRetryBackoff(backoff)
+	}
+	if backoff != maxRetryBackoff {
+		t.Errorf("expected %s after many retries, got %s", maxRetryBackoff, backoff)
+	}
+}
+
+func TestExecutionReportsCommandsThatCannotStart(t *testing.T) {
+	var output bytes.Buffer
+	report, execErr := ExecuteUserJob(context.Background(), missingCommandRunInput, &output, &output)
+	if execErr != nil {
+		t.Fatalf("received unexpected error: %s", execErr.Error())
+	}
+
+	if len(report.Results) != 1 || report.Results[0].ExitCode != -1 || report.Results[0].Err == nil {
+		t.Errorf("expected a start failure, got:\n%s", report.String())
+	}
+}
+
+func TestPrefixWriterSplitsLines(t *testing.T) {
+	var tests = []struct {
+		writes   []string
+		expected string
+	}{
+		{[]string{"one\ntwo\n"}, "> one\n> two\n"},
+		{[]string{"o", "ne\nt", "wo\n"}, "> one\n> two\n"},
+		{[]string{"one\n", "unterminated"}, "> one\n> unterminated\n"},
+		{[]string{"\n"}, "> \n"},
+		{[]string{}, ""},
+	}
+
+	for i, testCase := range tests {
+		var output bytes.Buffer
+		writer := &prefixWriter{out: &output, prefix: "> "}
+		for _, write := range testCase.writes {
+			writer.Write([]byte(write))
+		}
+		writer.Flush()
+
+		if output.String() != testCase.expected {
+			t.Errorf("test %d: expected %q, got %q", i, testCase.expected, output.String())
+		}
+	}
+}
+
+const successfulRunInput string = `
+- step: "create user 1"
+  dependencies: ["prepare database"]
+  precedence: 100
+  run:
+    command: sh
+    args: ["-c", "echo creating 1; printf partial"]
+- step: "create user 2"
+  dependencies: ["prepare database"]
+  precedence: 50
+- step: "prepare database"
+  dependencies: []
+  precedence: 10
+  run:
+    command: sh
+    args: ["-c", "echo preparing $DB_NAME"]
+    env:
+      DB_NAME: users
+`
+
+var successfulRunOutput = []string{
+	"prepare database",
+	"create user 1",
+	"create user 2",
+}
+
+const failingRunInput string = `
+- step: "create user 1"
+  dependencies: ["prepare database"]
+  precedence: 100
+  run:
+    command: sh
+    args: ["-c", "exit 3"]
+- step: "create user 2"
+  dependencies: ["prepare database"]
+  precedence: 50
+  run:
+    command: sh
+    args: ["-c", "exit 0"]
+- step: "prepare database"
+  dependencies: []
+  precedence: 10
+`
+
+const branchingRunInput string = `
+- step: "build web"
+  dependencies: []
+  precedence: 100
+  run:
+    command: sh
+    args: ["-c", "exit 1"]
+- step: "deploy web"
+  dependencies: ["build web"]
+  precedence: 100
+- step: "build api"
+  dependencies: []
+  precedence: 50
+- step: "deploy api"
+  dependencies: ["build api"]
+  precedence: 50
+- step: "smoke test"
+  dependencies: ["deploy web", "deploy api"]
+  precedence: 10
+`
+
+const flakyRunInput string = `
+- step: "flaky step"
+  dependencies: []
+  precedence: 100
+  retries: 2
+  retry_backoff: 10ms
+  run:
+    command: sh
+    args: ["-c", "n=$(($(cat $COUNT_FILE 2>/dev/null || echo 0) + 1)); echo $n > $COUNT_FILE; [ $n -ge $SUCCEED_ON ]"]
+- step: "after flaky step"
+  dependencies: ["flaky step"]
+  precedence: 100
+`
+
+const timeoutRunInput string = `
+- step: "slow step"
+  dependencies: []
+  precedence: 100
+  retries: 1
+  timeout: 200ms
+  run:
+    command: sh
+    args: ["-c", "sleep 30 & sleep 30"]
+`
+
+const missingCommandRunInput string = `
+- step: "prepare database"
+  dependencies: []
+  precedence: 10
+  run:
+    command: this-command-does-not-exist
+`
+
+const leftoverProcessRunInput string = `
+- step: "start daemon"
+  dependencies: []
+  precedence: 100
+  run:
+    command: sh
+    args: ["-c", "setsid sleep 10 & echo started"]
+`
@@ -0,0 +1,151 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Describes why a step landed where it did in the ordering. Positions are 1-based.
+type StepExplanation struct {
+	StepId                 string
+	Precedence             int64
+	Position               int                 // Where the step ended up in the ordering
+	ReadyAt                int                 // The first position the step could have filled
+	LastDependencyId       string              // The dependency that cleared last, empty if the step has none
+	LastDependencyPosition int                 // Where LastDependencyId ran, 0 if the step has no dependencies
+	ChosenAhead            []ChosenAheadOfStep // Steps picked while this step was ready and waiting
+}
+
+// A step that the scheduler picked instead of the explained step while it was ready to run
+type ChosenAheadOfStep struct {
+	StepId     string
+	Precedence int64
+	Position   int
+	Reason     string
+}
+
+func (explanation *StepExplanation) String() string {
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("step %s (precedence %d) runs at position %d\n",
+		explanation.StepId, explanation.Precedence, explanation.Position))
+
+	if explanation.LastDependencyId == "" {
+		builder.WriteString("it has no dependencies, so it was ready from position 1\n")
+	} else {
+		builder.WriteString(fmt.Sprintf("its last dependency to clear was %s at position %d, so it was ready from position %d\n",
+			explanation.LastDependencyId, explanation.LastDependencyPosition, explanation.ReadyAt))
+	}
+
+	if len(explanation.ChosenAhead) == 0 {
+		builder.WriteString("it ran as soon as it was ready\n")
+		return builder.String()
+	}
+
+	builder.WriteString(fmt.Sprintf("%d step(s) were chosen ahead of it while it waited:\n", len(explanation.ChosenAhead)))
+	for _, chosen := range explanation.ChosenAhead {
+		builder.WriteString(fmt.Sprintf("  %d. %s (precedence %d): %s\n", chosen.Position, chosen.StepId, chosen.Precedence, chosen.Reason))
+	}
+
+	return builder.String()
+}
+
+// Replays the scheduler over the user's job and explains the position of the given step
+func ExplainUserJobStep(yamlStr string, stepId string, opts ...Option) (*StepExplanation, error) {
+
+	ctx, cancel := NewProcessingContext(getOptions(opts).limits)
+	defer cancel()
+
+	job, jobErr := getValidatedJob(yamlStr, opts...)
+	if jobErr != nil {
+		return nil, jobErr
+	}
+
+	return explainStep(ctx, job.Steps, NormalizeStepId(stepId))
+}
+
+// Steps through the same choices the scheduler makes, noting when the target step became ready
+// and everything that was picked over it from then until it finally ran
+func explainStep(ctx context.Context, stepsByIdSlice []*Step, stepId string) (*StepExplanation, error) {
+
+	var target *Step
+	for _, step := range stepsByIdSlice {
+		if step.StepId == stepId {
+			target = step
+			break
+		}
+	}
+	if target == nil {
+		return nil, &ValidationError{StepIds: []string{stepId}, Message: fmt.Sprintf("step not found in job: %s", stepId)}
+	}
+
+	explanation := &StepExplanation{
+		StepId:      target.StepId,
+		Precedence:  target.Precedence,
+		ChosenAhead: make([]ChosenAheadOfStep, 0),
+	}
+
+	hasRun := make(map[string]bool, len(stepsByIdSlice))
+	positionsById := make(map[string]int, len(stepsByIdSlice))
+	for position := 1; ; position++ {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, fmt.Errorf("explanation stopped: %w", ctxErr)
+		}
+
+		nextStep := getBestReadyStep(stepsByIdSlice, hasRun)
+		if nextStep == nil {
+			return nil, &CycleError{StepIds: getUnrunStepIds(stepsByIdSlice, hasRun)}
+		}
+
+		targetReady := len(getUnrunDependencies(target, hasRun)) == 0
+		if targetReady && explanation.ReadyAt == 0 {
+			explanation.ReadyAt = position
+		}
+
+		if nextStep == target {
+			explanation.Position = position
+			break
+		}
+
+		if targetReady {
+			reason := "same precedence, lexicographically earlier"
+			if nextStep.Precedence != target.Precedence {
+				reason = fmt.Sprintf("higher precedence (%d > %d)", nextStep.Precedence, target.Precedence)
+			}
+			explanation.ChosenAhead = append(explanation.ChosenAhead, ChosenAheadOfStep{
+				StepId:     nextStep.StepId,
+				Precedence: nextStep.Precedence,
+				Position:   position,
+				Reason:     reason,
+			})
+		}
+
+		hasRun[nextStep.StepId] = true
+		positionsById[nextStep.StepId] = position
+	}
+
+	for _, parentStepId := range target.DependencyIds {
+		if positionsById[parentStepId] > explanation.LastDependencyPosition {
+			explanation.LastDependencyId = parentStepId
+			explanation.LastDependencyPosition = positionsById[parentStepId]
+		}
+	}
+
+	return explanation, nil
+}
+
+// Lists the steps the replay never got to, which is everything in or downstream of a cycle
+func getUnrunStepIds(stepsByIdSlice []*Step, hasRun map[string]bool) []string {
+
+	stepIds := make([]string, 0)
+	for _, step := range stepsByIdSlice {
+		if !hasRun[step.StepId] {
+			stepIds = append(stepIds, step.StepId)
+		}
+	}
+	sort.Strings(stepIds)
+
+	return stepIds
+}
@@ -0,0 +1,64 @@
+package scheduler
+
+import (
+	"testing"
+)
+
+func TestCorrectlyExplainsStepPositions(t *testing.T) {
+	var tests = []struct {
+		yamlInput        string
+		stepId           string
+		position         int
+		readyAt          int
+		lastDependencyId string
+		chosenAhead      []string
+	}{
+		{oneStepInput, "prepare database", 1, 1, "", []string{}},
+		{basicWithDependenciesInput, "create user 3", 5, 5, "create user 4", []string{}},
+		{basicWithDependenciesInput, " create user 2 ", 3, 2, "prepare database", []string{"create user 1"}},
+		{complexWithDependenciesInput, "create bucket", 5, 1, "", []string{"enable dns records", "deploy database", "deploy lambda function", "deploy api gateway"}},
+		{complexWithDependenciesInput, "enable cdn distribution", 6, 6, "create bucket", []string{}},
+	}
+
+	for i, testCase := range tests {
+		explanation, explainErr := ExplainUserJobStep(testCase.yamlInput, testCase.stepId)
+		if explainErr != nil {
+			t.Errorf("test %d: expected success, got error: %s", i, explainErr.Error())
+			continue
+		}
+
+		if explanation.Position != testCase.position || explanation.ReadyAt != testCase.readyAt {
+			t.Errorf("test %d: expected position %d ready at %d, got: %s", i, testCase.position, testCase.readyAt, explanation.String())
+		}
+
+		if explanation.LastDependencyId != testCase.lastDependencyId {
+			t.Errorf("test %d: expected last dependency %q, got %q", i, testCase.lastDependencyId, explanation.LastDependencyId)
+		}
+
+		chosenAheadIds := make([]string, len(explanation.ChosenAhead))
+		for j, chosen := range explanation.ChosenAhead {
+			chosenAheadIds[j] = chosen.StepId
+		}
+		if !areStringSlicesEqual(chosenAheadIds, testCase.chosenAhead) {
+			t.Errorf("test %d: did not get equal chosen ahead steps, got: %v", i, chosenAheadIds)
+		}
+	}
+}
+
+func TestExplainRejectsUnknownAndBlockedSteps(t *testing.T) {
+	var tests = []struct {
+		yamlInput string
+		stepId    string
+	}{
+		{basicWithDependenciesInput, "create user 9"},
+		{circularDependenciesInput, "deploy lambda function"},
+		{nonYamlStringInput, "deploy lambda function"},
+	}
+
+	for i, testCase := range tests {
+		_, explainErr := ExplainUserJobStep(testCase.yamlInput, testCase.stepId)
+		if explainErr == nil {
+			t.Errorf("test %d: should have received error, did not", i)
+		}
+	}
+}
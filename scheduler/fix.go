@@ -0,0 +1,105 @@
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Applies the fixes that can't change what the user meant: trimming whitespace from step and
+// dependency IDs, dropping empty and duplicate dependency entries, and correcting dependencies
+// that are an unambiguous near-miss of an existing step ID. Returns the fixed YAML (unchanged if
+// nothing needed fixing) and a description of each fix. Anything we can't safely fix, such as a
+// cycle or a missing precedence, is left alone and comes back as the usual processing error.
+func FixUserJob(yamlStr string, opts ...Option) (string, []string, error) {
+
+	fixes := make([]string, 0)
+
+	doc, stepNodes, docErr := getJobDocument(yamlStr)
+	if docErr != nil {
+		return yamlStr, fixes, docErr
+	}
+
+	stepIds := make([]string, 0, len(stepNodes))
+	stepIdSet := make(map[string]bool, len(stepNodes))
+	for i, stepNode := range stepNodes {
+		stepValue := getMappingValue(stepNode, "step")
+		if stepValue == nil || stepValue.Kind != yaml.ScalarNode {
+			continue
+		}
+
+		stepId := NormalizeStepId(stepValue.Value)
+		if stepId != stepValue.Value && stepId != "" {
+			fixes = append(fixes, fmt.Sprintf("step %d: %s step %q", i+1, getNormalizationFix(stepValue.Value), stepValue.Value))
+			stepValue.Value = stepId
+		}
+
+		stepIds = append(stepIds, stepId)
+		stepIdSet[stepId] = true
+	}
+
+	for i, stepNode := range stepNodes {
+		dependenciesValue := getMappingValue(stepNode, "dependencies")
+		if dependenciesValue == nil || dependenciesValue.Kind != yaml.SequenceNode {
+			continue
+		}
+
+		dependencyNodes := make([]*yaml.Node, 0, len(dependenciesValue.Content))
+		seen := make(map[string]bool, len(dependenciesValue.Content))
+		for _, dependencyNode := range dependenciesValue.Content {
+			if dependencyNode.Kind != yaml.ScalarNode {
+				dependencyNodes = append(dependencyNodes, dependencyNode)
+				continue
+			}
+
+			dependencyId := NormalizeStepId(dependencyNode.Value)
+			if dependencyId == "" {
+				fixes = append(fixes, fmt.Sprintf("step %d: dropped empty dependency", i+1))
+				continue
+			}
+
+			if dependencyId != dependencyNode.Value {
+				fixes = append(fixes, fmt.Sprintf("step %d: %s dependency %q", i+1, getNormalizationFix(dependencyNode.Value), dependencyNode.Value))
+			}
+
+			if !stepIdSet[dependencyId] {
+				if correctedId, isNearMiss := getNearMissStepId(dependencyId, stepIds); isNearMiss {
+					fixes = append(fixes, fmt.Sprintf("step %d: corrected dependency %q to %q", i+1, dependencyId, correctedId))
+					dependencyId = correctedId
+				}
+			}
+
+			if seen[dependencyId] {
+				fixes = append(fixes, fmt.Sprintf("step %d: dropped duplicate dependency %q", i+1, dependencyId))
+				continue
+			}
+			seen[dependencyId] = true
+
+			dependencyNode.Value = dependencyId
+			dependencyNodes = append(dependencyNodes, dependencyNode)
+		}
+		dependenciesValue.Content = dependencyNodes
+	}
+
+	fixed := yamlStr
+	if len(fixes) > 0 {
+		var encodeErr error
+		fixed, encodeErr = encodeJobDocument(doc)
+		if encodeErr != nil {
+			return yamlStr, fixes, encodeErr
+		}
+	}
+
+	_, processingErr := ProcessUserJob(fixed, opts...)
+
+	return fixed, fixes, processingErr
+}
+
+// Describes what NormalizeStepId did to a raw ID, for the fix summary
+func getNormalizationFix(raw string) string {
+	if strings.TrimSpace(raw) != raw {
+		return "trimmed whitespace from"
+	}
+	return "normalized unicode in"
+}
@@ -0,0 +1,124 @@
+package scheduler
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCorrectlyFixesJobs(t *testing.T) {
+	var tests = []struct {
+		yamlInput     string
+		expectedFixes int
+		correctOutput []string
+	}{
+		{basicWithDependenciesInput, 0, basicWithDependenciesOutput},
+		{complexWithDependenciesMultipleStepLeadingTrailingWhitespaceInput, 5, complexWithDependenciesOutput},
+		{singleEmptyDependency, 1, []string{"enable dns records", "deploy api gateway", "deploy database", "deploy lambda function", "create bucket", "enable cdn distribution"}},
+		{singleInvalidDependency, 1, []string{"enable dns records", "deploy databaseE", "deploy lambda function", "deploy api gateway", "create bucket", "enable cdn distribution"}},
+		{multipleInvalidDependency, 3, []string{"enable dns records", "deploy database", "deploy lambda function", "deploy api gateway", "reate bucket", "enable cdn distribution"}},
+		{fixableTyposInput, 4, basicWithDependenciesOutput},
+	}
+
+	for i, testCase := range tests {
+		fixed, fixes, fixErr := FixUserJob(testCase.yamlInput)
+		if fixErr != nil {
+			t.Errorf("test %d: expected success, got error: %s", i, fixErr.Error())
+		}
+
+		if len(fixes) != testCase.expectedFixes {
+			t.Errorf("test %d: expected %d fixes, got: %v", i, testCase.expectedFixes, fixes)
+		}
+
+		if len(fixes) == 0 && fixed != testCase.yamlInput {
+			t.Errorf("test %d: job was rewritten without any fixes", i)
+		}
+
+		output, outputErr := ProcessUserJob(fixed)
+		if outputErr != nil {
+			t.Errorf("test %d: fixed job did not process: %s", i, outputErr.Error())
+		}
+		if !areStringSlicesEqual(output, testCase.correctOutput) {
+			t.Errorf("test %d: did not get equal string arrays, got: %v", i, output)
+		}
+	}
+}
+
+func TestFixLeavesUnfixableErrors(t *testing.T) {
+	var tests = []struct {
+		yamlInput string
+	}{
+		{circularDependenciesInput},
+		{singleMissingPrecedenceFieldInput},
+		{nonYamlStringInput},
+		{ambiguousTypoInput},
+		{ambiguousLetterTypoInput},
+		{digitNearMissInput},
+	}
+
+	for i, testCase := range tests {
+		fixed, fixes, fixErr := FixUserJob(testCase.yamlInput)
+		if fixErr == nil {
+			t.Errorf("test %d: should have received error, did not", i)
+		}
+		for _, fix := range fixes {
+			if strings.Contains(fix, "corrected dependency") {
+				t.Errorf("test %d: a dependency was pointed at a different step: %s", i, fix)
+			}
+		}
+		if len(fixes) == 0 && fixed != testCase.yamlInput {
+			t.Errorf("test %d: job was rewritten without any fixes", i)
+		}
+	}
+}
+
+const fixableTyposInput string = `
+- step: "create user 1"
+  dependencies: ["prepare database", "prepare database"]
+  precedence: 100
+- step: "create user 2"
+  dependencies: ["Prepare Database"]
+  precedence: 50
+- step: "prepare database "
+  dependencies: []
+  precedence: 10
+- step: "create user 3"
+  dependencies: ["create usr 4"]
+  precedence: 10
+- step: "create user 4"
+  dependencies: ["create user 2"]
+  precedence: 100
+`
+
+const ambiguousTypoInput string = `
+- step: "create user 1"
+  dependencies: []
+  precedence: 100
+- step: "create user 2"
+  dependencies: []
+  precedence: 50
+- step: "create user 3"
+  dependencies: ["create user"]
+  precedence: 10
+`
+
+// build apx is one edit from both build api and build app
+const ambiguousLetterTypoInput string = `
+- step: "build api"
+  dependencies: []
+  precedence: 100
+- step: "build app"
+  dependencies: []
+  precedence: 50
+- step: "deploy"
+  dependencies: ["build apx"]
+  precedence: 10
+`
+
+const digitNearMissInput string = `
+- step: "deploy v1"
+  dependencies: []
+  precedence: 100
+- step: "smoke test"
+  dependencies: ["deploy v2"]
+  precedence: 50
+`
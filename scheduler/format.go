@@ -0,0 +1,153 @@
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Step orderings that the formatter can apply
+const (
+	FormatOrderNone         = "none"         // Keep steps where the user put them
+	FormatOrderAlphabetical = "alphabetical" // Sort steps by ID
+	FormatOrderTopological  = "topological"  // Sort steps into the order the scheduler runs them
+)
+
+// The key order every formatted step is written in. Keys we don't recognise are kept after these.
+var canonicalStepKeys = []string{"step", "dependencies", "precedence"}
+
+// Rewrites the user's job into canonical form: trimmed and quoted IDs, deduplicated flow-style
+// dependency lists (always present, even when empty), plain integer precedences and a consistent
+// key order. This works on the yaml.Node tree rather than InputJobStep so comments survive.
+// The job must be valid, so we never guess at how to rewrite something we'd reject anyway.
+func FormatUserJob(yamlStr string, stepOrder string, opts ...Option) (string, error) {
+
+	ctx, cancel := NewProcessingContext(getOptions(opts).limits)
+	defer cancel()
+
+	job, jobErr := getValidatedJob(yamlStr, opts...)
+	if jobErr != nil {
+		return "", jobErr
+	}
+
+	ordering, scheduleErr := scheduleSteps(ctx, job.Steps)
+	if scheduleErr != nil {
+		return "", scheduleErr
+	}
+
+	doc, _, docErr := getJobDocument(yamlStr)
+	if docErr != nil {
+		return "", docErr
+	}
+	stepsNode := doc.Content[0]
+
+	stepIdsByNode := make(map[*yaml.Node]string, len(stepsNode.Content))
+	for i, stepNode := range stepsNode.Content {
+		stepId, formatErr := formatStepNode(stepNode)
+		if formatErr != nil {
+			return "", fmt.Errorf("could not format step %d: %w", i+1, formatErr)
+		}
+		stepIdsByNode[stepNode] = stepId
+	}
+
+	switch stepOrder {
+	case FormatOrderNone, "":
+	case FormatOrderAlphabetical:
+		sort.SliceStable(stepsNode.Content, func(i, j int) bool {
+			return stepIdsByNode[stepsNode.Content[i]] < stepIdsByNode[stepsNode.Content[j]]
+		})
+	case FormatOrderTopological:
+		positions := getPositionsById(ordering)
+		sort.SliceStable(stepsNode.Content, func(i, j int) bool {
+			return positions[stepIdsByNode[stepsNode.Content[i]]] < positions[stepIdsByNode[stepsNode.Content[j]]]
+		})
+	default:
+		return "", fmt.Errorf("unknown step order: %s", stepOrder)
+	}
+
+	return encodeJobDocument(doc)
+}
+
+// Canonicalizes a single step mapping in place, returning its step ID
+func formatStepNode(stepNode *yaml.Node) (string, error) {
+
+	// Mapping content alternates key, value, key, value...
+	valuesByKey := make(map[string]*yaml.Node)
+	keysByKey := make(map[string]*yaml.Node)
+	otherPairs := make([]*yaml.Node, 0)
+	for i := 0; i+1 < len(stepNode.Content); i += 2 {
+		keyNode, valueNode := stepNode.Content[i], stepNode.Content[i+1]
+		if containsString(canonicalStepKeys, keyNode.Value) {
+			keysByKey[keyNode.Value] = keyNode
+			valuesByKey[keyNode.Value] = valueNode
+		} else {
+			otherPairs = append(otherPairs, keyNode, valueNode)
+		}
+	}
+
+	// Anchors and merge keys can supply these without them appearing in the mapping itself
+	stepValue, precedenceValue := valuesByKey["step"], valuesByKey["precedence"]
+	if stepValue == nil || precedenceValue == nil || stepValue.Kind != yaml.ScalarNode || precedenceValue.Kind != yaml.ScalarNode {
+		return "", &ValidationError{Message: "step and precedence must be written directly on the step"}
+	}
+
+	stepValue.Value = NormalizeStepId(stepValue.Value)
+	stepValue.Tag = "!!str"
+	stepValue.Style = yaml.DoubleQuotedStyle
+
+	precedence, parseErr := strconv.ParseInt(strings.TrimSpace(precedenceValue.Value), 10, 64)
+	if parseErr != nil {
+		return "", &ValidationError{StepIds: []string{stepValue.Value}, Message: fmt.Sprintf("invalid precedence: %s", precedenceValue.Value)}
+	}
+	precedenceValue.Value = strconv.FormatInt(precedence, 10)
+	precedenceValue.Tag = "!!int"
+	precedenceValue.Style = 0
+
+	if _, hasDependencies := valuesByKey["dependencies"]; !hasDependencies {
+		keysByKey["dependencies"] = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "dependencies"}
+		valuesByKey["dependencies"] = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	}
+
+	dependenciesValue := valuesByKey["dependencies"]
+	if dependenciesValue.Kind != yaml.SequenceNode && dependenciesValue.Tag != "!!null" {
+		return "", &ValidationError{StepIds: []string{stepValue.Value}, Message: "dependencies must be written directly on the step as a list"}
+	}
+	dependencyNodes := make([]*yaml.Node, 0, len(dependenciesValue.Content))
+	dependenciesStyle := yaml.FlowStyle
+	seen := make(map[string]bool, len(dependenciesValue.Content))
+	for _, dependencyNode := range dependenciesValue.Content {
+		// Comments on individual entries only survive in block style
+		if dependencyNode.HeadComment != "" || dependencyNode.LineComment != "" || dependencyNode.FootComment != "" {
+			dependenciesStyle = 0
+		}
+
+		dependencyId := NormalizeStepId(dependencyNode.Value)
+		if seen[dependencyId] {
+			continue
+		}
+		seen[dependencyId] = true
+
+		dependencyNode.Value = dependencyId
+		dependencyNode.Tag = "!!str"
+		dependencyNode.Style = yaml.DoubleQuotedStyle
+		dependencyNodes = append(dependencyNodes, dependencyNode)
+	}
+
+	// An explicit null (a bare "dependencies:") becomes an empty list
+	dependenciesValue.Kind = yaml.SequenceNode
+	dependenciesValue.Tag = "!!seq"
+	dependenciesValue.Value = ""
+	dependenciesValue.Style = dependenciesStyle
+	dependenciesValue.Content = dependencyNodes
+
+	content := make([]*yaml.Node, 0, len(stepNode.Content)+2)
+	for _, key := range canonicalStepKeys {
+		content = append(content, keysByKey[key], valuesByKey[key])
+	}
+	stepNode.Content = append(content, otherPairs...)
+
+	return stepValue.Value, nil
+}
@@ -0,0 +1,132 @@
+package scheduler
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCorrectlyFormatsJobs(t *testing.T) {
+	var tests = []struct {
+		yamlInput     string
+		stepOrder     string
+		correctOutput string
+	}{
+		{unformattedInput, FormatOrderNone, formattedOutput},
+		{formattedOutput, FormatOrderNone, formattedOutput},
+		{unformattedInput, FormatOrderAlphabetical, formattedOutput}, // Already alphabetical
+		{unformattedInput, FormatOrderTopological, formattedTopologicalOutput},
+	}
+
+	for i, testCase := range tests {
+		output, formatErr := FormatUserJob(testCase.yamlInput, testCase.stepOrder)
+		if formatErr != nil {
+			t.Errorf("test %d: expected success, got error: %s", i, formatErr.Error())
+		}
+
+		if output != testCase.correctOutput {
+			t.Errorf("test %d: did not get formatted output, got:\n%s", i, output)
+		}
+	}
+}
+
+func TestFormattingIsIdempotentAndKeepsOrdering(t *testing.T) {
+	var tests = []struct {
+		validYamlInput string
+		correctOutput  []string
+	}{
+		{awsInfraInput, awsInfraOutput},
+		{complexValidInput, complexValidOutput},
+		{multipleNoDependenciesLinePresentInput, multipleNoDependenciesOutput},
+		{complexWithDependenciesMultipleStepLeadingTrailingWhitespaceInput, complexWithDependenciesOutput},
+		{multipleDependenciesWithTrailingWhitespaceInputInput, complexWithDependenciesOutput},
+	}
+
+	for i, testCase := range tests {
+		for _, stepOrder := range []string{FormatOrderNone, FormatOrderAlphabetical, FormatOrderTopological} {
+			formatted, formatErr := FormatUserJob(testCase.validYamlInput, stepOrder)
+			if formatErr != nil {
+				t.Errorf("test %d (%s): expected success, got error: %s", i, stepOrder, formatErr.Error())
+				continue
+			}
+
+			reformatted, _ := FormatUserJob(formatted, stepOrder)
+			if reformatted != formatted {
+				t.Errorf("test %d (%s): formatting is not idempotent, got:\n%s", i, stepOrder, reformatted)
+			}
+
+			output, _ := ProcessUserJob(formatted)
+			if !areStringSlicesEqual(output, testCase.correctOutput) {
+				t.Errorf("test %d (%s): formatting changed the ordering, got: %v", i, stepOrder, output)
+			}
+		}
+	}
+}
+
+func TestFormatRejectsInvalidJobs(t *testing.T) {
+	var tests = []struct {
+		yamlInput string
+		stepOrder string
+	}{
+		{circularDependenciesInput, FormatOrderNone},
+		{singleInvalidDependency, FormatOrderNone},
+		{nonYamlStringInput, FormatOrderNone},
+		{basicWithDependenciesInput, "random"},
+	}
+
+	for i, testCase := range tests {
+		if _, formatErr := FormatUserJob(testCase.yamlInput, testCase.stepOrder); formatErr == nil {
+			t.Errorf("test %d: should have received error, did not", i)
+		}
+	}
+}
+
+func TestFormatKeepsComments(t *testing.T) {
+	output, formatErr := FormatUserJob(unformattedInput, FormatOrderAlphabetical)
+	if formatErr != nil {
+		t.Fatalf("expected success, got error: %s", formatErr.Error())
+	}
+
+	for _, comment := range []string{"# users come after the database", "# must be first", "# see ticket"} {
+		if !strings.Contains(output, comment) {
+			t.Errorf("comment %q was lost", comment)
+		}
+	}
+}
+
+const unformattedInput string = `# users come after the database
+- precedence: "100"
+  step: "  create user 1"
+  dependencies: ["prepare database", " prepare database"]
+- step: create user 2
+  dependencies:
+    - prepare database # see ticket
+  precedence:  050
+- step: prepare database # must be first
+  precedence: 10
+`
+
+const formattedOutput string = `# users come after the database
+- step: "create user 1"
+  dependencies: ["prepare database"]
+  precedence: 100
+- step: "create user 2"
+  dependencies:
+    - "prepare database" # see ticket
+  precedence: 50
+- step: "prepare database" # must be first
+  dependencies: []
+  precedence: 10
+`
+
+const formattedTopologicalOutput string = `- step: "prepare database" # must be first
+  dependencies: []
+  precedence: 10
+# users come after the database
+- step: "create user 1"
+  dependencies: ["prepare database"]
+  precedence: 100
+- step: "create user 2"
+  dependencies:
+    - "prepare database" # see ticket
+  precedence: 50
+`
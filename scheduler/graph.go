@@ -0,0 +1,75 @@
+package scheduler
+
+import (
+	"sort"
+)
+
+// Indexes the validated steps by StepId
+func getStepsByIdMap(stepsByIdSlice []*Step) map[string]*Step {
+
+	stepsByIdMap := make(map[string]*Step, len(stepsByIdSlice))
+	for _, step := range stepsByIdSlice {
+		stepsByIdMap[step.StepId] = step
+	}
+
+	return stepsByIdMap
+}
+
+// Returns the set of every step that stepId depends on, directly or indirectly. This walks
+// DependencyIds rather than depsToClear, since the latter is emptied out as the scheduler runs.
+func getTransitiveDependencies(stepsByIdMap map[string]*Step, stepId string) map[string]bool {
+
+	visited := make(map[string]bool)
+	toVisit := []string{stepId}
+	for len(toVisit) > 0 {
+		currentId := toVisit[len(toVisit)-1]
+		toVisit = toVisit[:len(toVisit)-1]
+
+		step, stepOk := stepsByIdMap[currentId]
+		if !stepOk {
+			continue
+		}
+
+		for _, parentStepId := range step.DependencyIds {
+			if !visited[parentStepId] {
+				visited[parentStepId] = true
+				toVisit = append(toVisit, parentStepId)
+			}
+		}
+	}
+
+	return visited
+}
+
+// Dependencies as a set, so duplicate entries in the YAML don't show up as changes
+func getDependencySet(step *Step) map[string]bool {
+
+	deps := make(map[string]bool, len(step.DependencyIds))
+	for _, parentStepId := range step.DependencyIds {
+		deps[parentStepId] = true
+	}
+
+	return deps
+}
+
+func getSortedStepIds(stepsByIdMap map[string]*Step) []string {
+
+	stepIds := make([]string, 0, len(stepsByIdMap))
+	for stepId := range stepsByIdMap {
+		stepIds = append(stepIds, stepId)
+	}
+	sort.Strings(stepIds)
+
+	return stepIds
+}
+
+func getSortedKeys(set map[string]bool) []string {
+
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
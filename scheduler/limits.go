@@ -0,0 +1,122 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Caps that protect the scheduler from hostile or runaway inputs. A zero value means no cap.
+type ResourceLimits struct {
+	MaxInputBytes          int64         // Largest input file we'll read
+	MaxSteps               int           // Most steps a job may have
+	MaxDependenciesPerStep int           // Most entries in any one dependencies list
+	MaxStepIdLength        int           // Longest step or dependency ID, in characters
+	MaxAliasExpansions     int           // Most YAML aliases resolved while decoding, counting nested ones
+	Timeout                time.Duration // Longest ProcessUserJob may take
+}
+
+// The limits used unless WithLimits says otherwise
+func DefaultResourceLimits() ResourceLimits {
+	return ResourceLimits{
+		MaxInputBytes:          10 * 1024 * 1024,
+		MaxSteps:               10000,
+		MaxDependenciesPerStep: 1000,
+		MaxStepIdLength:        1024,
+		MaxAliasExpansions:     10000,
+		Timeout:                30 * time.Second,
+	}
+}
+
+// Returns a context that expires after the limits' timeout, if there is one. Every entry point
+// that takes Options uses it; callers of the staged API can use it to apply the same timeout.
+func NewProcessingContext(limits ResourceLimits) (context.Context, context.CancelFunc) {
+	if limits.Timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), limits.Timeout)
+}
+
+// Counts the aliases that decoding the node would resolve, including aliases inside aliased
+// content, which is how alias bombs blow up. Counts are memoized per node so the walk itself
+// stays linear, and saturate just past maxExpansions so they can't overflow.
+func countAliasExpansions(node *yaml.Node, maxExpansions int, memo map[*yaml.Node]int) int {
+
+	if count, seen := memo[node]; seen {
+		return count
+	}
+
+	// Guards against an alias that (indirectly) refers to itself
+	memo[node] = maxExpansions + 1
+
+	count := 0
+	if node.Kind == yaml.AliasNode && node.Alias != nil {
+		count = 1 + countAliasExpansions(node.Alias, maxExpansions, memo)
+	}
+	for _, child := range node.Content {
+		if count > maxExpansions {
+			break
+		}
+		count += countAliasExpansions(child, maxExpansions, memo)
+	}
+	if count > maxExpansions {
+		count = maxExpansions + 1
+	}
+
+	memo[node] = count
+	return count
+}
+
+// Checks the parsed document against the alias expansion cap before it's decoded
+func checkAliasExpansionLimit(doc *yaml.Node, limits ResourceLimits) error {
+
+	if limits.MaxAliasExpansions <= 0 {
+		return nil
+	}
+
+	if countAliasExpansions(doc, limits.MaxAliasExpansions, make(map[*yaml.Node]int)) > limits.MaxAliasExpansions {
+		return &LimitError{Limit: "max-alias-expansions", Message: fmt.Sprintf("yaml expands more than %d aliases", limits.MaxAliasExpansions)}
+	}
+
+	return nil
+}
+
+// Checks the decoded steps against the step count, dependency count and ID length caps
+func checkStepLimits(inputSteps []*InputJobStep, limits ResourceLimits) error {
+
+	if limits.MaxSteps > 0 && len(inputSteps) > limits.MaxSteps {
+		return &LimitError{Limit: "max-steps", Message: fmt.Sprintf("job has %d steps, more than the limit of %d", len(inputSteps), limits.MaxSteps)}
+	}
+
+	for i, inputStep := range inputSteps {
+		if inputStep == nil {
+			continue
+		}
+
+		if limits.MaxDependenciesPerStep > 0 && len(inputStep.Dependencies) > limits.MaxDependenciesPerStep {
+			return &LimitError{
+				Limit:   "max-dependencies",
+				Message: fmt.Sprintf("step %d has %d dependencies, more than the limit of %d", i+1, len(inputStep.Dependencies), limits.MaxDependenciesPerStep),
+			}
+		}
+
+		if limits.MaxStepIdLength <= 0 {
+			continue
+		}
+
+		if utf8.RuneCountInString(NormalizeStepId(inputStep.StepName)) > limits.MaxStepIdLength {
+			return &LimitError{Limit: "max-id-length", Message: fmt.Sprintf("step %d has an ID longer than the limit of %d characters", i+1, limits.MaxStepIdLength)}
+		}
+
+		for _, depIdStr := range inputStep.Dependencies {
+			if utf8.RuneCountInString(NormalizeStepId(depIdStr)) > limits.MaxStepIdLength {
+				return &LimitError{Limit: "max-id-length", Message: fmt.Sprintf("step %d has a dependency ID longer than the limit of %d characters", i+1, limits.MaxStepIdLength)}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package scheduler
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCorrectlyRejectsInputsOverLimits(t *testing.T) {
+	var tests = []struct {
+		yamlInput string
+		limits    ResourceLimits
+	}{
+		{basicWithDependenciesInput, ResourceLimits{MaxSteps: 4}},
+		{complexWithDependenciesInput, ResourceLimits{MaxDependenciesPerStep: 3}},
+		{basicWithDependenciesInput, ResourceLimits{MaxStepIdLength: 12}},
+		{basicWithDependenciesInput, ResourceLimits{MaxInputBytes: 100}},
+		{aliasBombInput, DefaultResourceLimits()},
+		{aliasedStepsInput, ResourceLimits{MaxAliasExpansions: 1}},
+	}
+
+	for i, testCase := range tests {
+		_, outputErr := ProcessUserJob(testCase.yamlInput, WithLimits(testCase.limits))
+		if outputErr == nil {
+			t.Errorf("test %d: should have received error, did not", i)
+		}
+	}
+}
+
+func TestCorrectlyProcessesInputsWithinLimits(t *testing.T) {
+	var tests = []struct {
+		yamlInput     string
+		limits        ResourceLimits
+		correctOutput []string
+	}{
+		{basicWithDependenciesInput, ResourceLimits{MaxSteps: 5, MaxDependenciesPerStep: 1, MaxStepIdLength: 16}, basicWithDependenciesOutput},
+		{basicWithDependenciesInput, ResourceLimits{MaxInputBytes: int64(len(basicWithDependenciesInput))}, basicWithDependenciesOutput},
+		{aliasedStepsInput, DefaultResourceLimits(), []string{"prepare database", "create user 1", "create user 2"}},
+		{aliasedStepsInput, ResourceLimits{}, []string{"prepare database", "create user 1", "create user 2"}},
+	}
+
+	for i, testCase := range tests {
+		output, outputErr := ProcessUserJob(testCase.yamlInput, WithLimits(testCase.limits))
+		if outputErr != nil {
+			t.Errorf("test %d: expected success, got error: %s", i, outputErr.Error())
+		}
+
+		if !areStringSlicesEqual(output, testCase.correctOutput) {
+			t.Errorf("test %d: did not get equal string arrays, got: %v", i, output)
+		}
+	}
+}
+
+func TestProcessingStopsWhenContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, outputErr := ProcessUserJobWithContext(ctx, complexValidInput)
+	if outputErr == nil || !strings.Contains(outputErr.Error(), "context canceled") {
+		t.Errorf("expected cancellation error, got: %v", outputErr)
+	}
+}
+
+// Each level of aliases multiplies the one before it by ten
+const aliasBombInput string = `
+- step: &a ["lol", "lol", "lol", "lol", "lol", "lol", "lol", "lol", "lol", "lol"]
+  dependencies: &b [*a, *a, *a, *a, *a, *a, *a, *a, *a, *a]
+  precedence: &c [*b, *b, *b, *b, *b, *b, *b, *b, *b, *b]
+- step: &d [*c, *c, *c, *c, *c, *c, *c, *c, *c, *c]
+  dependencies: &e [*d, *d, *d, *d, *d, *d, *d, *d, *d, *d]
+  precedence: [*e, *e, *e, *e, *e, *e, *e, *e, *e, *e]
+`
+
+const aliasedStepsInput string = `
+- step: &db "prepare database"
+  precedence: &high 100
+- step: "create user 1"
+  dependencies: [*db]
+  precedence: *high
+- step: "create user 2"
+  dependencies: [*db]
+  precedence: 50
+`
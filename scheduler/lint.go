@@ -0,0 +1,210 @@
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Lint rule names
+const (
+	LintRedundantDependency = "redundant-dependency" // A direct dependency that's already implied by another dependency
+	LintDuplicateDependency = "duplicate-dependency" // The same ID listed more than once in one dependencies list
+	LintUntrimmedStepName   = "untrimmed-step-name"  // A step value with leading or trailing whitespace
+	LintIsolatedStep        = "isolated-step"        // A step with no dependencies and no dependents
+	LintPrecedenceInversion = "precedence-inversion" // A step depending on a much lower-precedence step
+)
+
+// Lint severities. Only error findings cause the lint command to fail.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+	SeverityOff     = "off"
+)
+
+// Per-rule configuration. Ratio only applies to precedence-inversion: a dependency is flagged
+// when the dependent step's precedence is at least Ratio times the dependency's.
+type LintRuleConfig struct {
+	Severity string `yaml:"severity"`
+	Ratio    int64  `yaml:"ratio"`
+}
+
+// Lint configuration, keyed by rule name
+type LintConfig map[string]LintRuleConfig
+
+// A single lint finding against a step
+type LintFinding struct {
+	Rule     string
+	Severity string
+	StepId   string
+	Message  string
+}
+
+func (finding LintFinding) String() string {
+	return fmt.Sprintf("%s [%s] %s: %s", finding.Severity, finding.Rule, finding.StepId, finding.Message)
+}
+
+// Returns the configuration used when the user doesn't override a rule
+func DefaultLintConfig() LintConfig {
+	return LintConfig{
+		LintRedundantDependency: {Severity: SeverityWarning},
+		LintDuplicateDependency: {Severity: SeverityWarning},
+		LintUntrimmedStepName:   {Severity: SeverityWarning},
+		LintIsolatedStep:        {Severity: SeverityInfo},
+		LintPrecedenceInversion: {Severity: SeverityWarning, Ratio: 100},
+	}
+}
+
+// Parses a YAML lint config and layers it over the defaults. Rules left out of the
+// YAML, and fields left out of a rule, keep their default values.
+func ParseLintConfig(yamlStr string) (LintConfig, error) {
+
+	overrides := make(LintConfig)
+	yamlMarshalErr := yaml.Unmarshal([]byte(yamlStr), &overrides)
+	if yamlMarshalErr != nil {
+		return nil, &ParseError{Err: yamlMarshalErr}
+	}
+
+	config := DefaultLintConfig()
+	for rule, override := range overrides {
+		if _, ruleOk := config[rule]; !ruleOk {
+			return nil, &ValidationError{Message: fmt.Sprintf("unknown lint rule: %s", rule)}
+		}
+		if override.Severity != "" {
+			if setErr := config.SetSeverity(rule, override.Severity); setErr != nil {
+				return nil, setErr
+			}
+		}
+		if override.Ratio != 0 {
+			if override.Ratio < 1 {
+				return nil, &ValidationError{Message: fmt.Sprintf("ratio for %s must be a positive integer", rule)}
+			}
+			ruleConfig := config[rule]
+			ruleConfig.Ratio = override.Ratio
+			config[rule] = ruleConfig
+		}
+	}
+
+	return config, nil
+}
+
+// Overrides the severity of a single rule, rejecting unknown rules and severities
+func (config LintConfig) SetSeverity(rule string, severity string) error {
+
+	ruleConfig, ruleOk := config[rule]
+	if !ruleOk {
+		return &ValidationError{Message: fmt.Sprintf("unknown lint rule: %s", rule)}
+	}
+
+	switch severity {
+	case SeverityError, SeverityWarning, SeverityInfo, SeverityOff:
+	default:
+		return &ValidationError{Message: fmt.Sprintf("unknown severity for %s: %s", rule, severity)}
+	}
+
+	ruleConfig.Severity = severity
+	config[rule] = ruleConfig
+
+	return nil
+}
+
+// Validates the user's job as usual, then checks it against each enabled lint rule. Hard
+// errors (including cycles) come back as an error; hygiene problems come back as findings.
+func LintUserJob(yamlStr string, config LintConfig, opts ...Option) ([]LintFinding, error) {
+
+	ctx, cancel := NewProcessingContext(getOptions(opts).limits)
+	defer cancel()
+
+	job, jobErr := getValidatedJob(yamlStr, opts...)
+	if jobErr != nil {
+		return make([]LintFinding, 0), jobErr
+	}
+
+	findings := lintSteps(job.Steps, config)
+
+	if _, scheduleErr := scheduleSteps(ctx, job.Steps); scheduleErr != nil {
+		return findings, scheduleErr
+	}
+
+	return findings, nil
+}
+
+func lintSteps(stepsByIdSlice []*Step, config LintConfig) []LintFinding {
+
+	findings := make([]LintFinding, 0)
+	report := func(rule string, stepId string, message string) {
+		severity := config[rule].Severity
+		if severity == "" || severity == SeverityOff {
+			return
+		}
+		findings = append(findings, LintFinding{Rule: rule, Severity: severity, StepId: stepId, Message: message})
+	}
+
+	stepsByIdMap := getStepsByIdMap(stepsByIdSlice)
+
+	hasDependents := make(map[string]bool, len(stepsByIdSlice))
+	for _, step := range stepsByIdSlice {
+		for _, parentStepId := range step.DependencyIds {
+			hasDependents[parentStepId] = true
+		}
+	}
+
+	for _, step := range stepsByIdSlice {
+
+		if strings.TrimSpace(step.StepName) != step.StepName {
+			report(LintUntrimmedStepName, step.StepId, fmt.Sprintf("step value %q has leading or trailing whitespace", step.StepName))
+		}
+
+		if len(stepsByIdSlice) > 1 && len(step.DependencyIds) == 0 && !hasDependents[step.StepId] {
+			report(LintIsolatedStep, step.StepId, "step has no dependencies and nothing depends on it")
+		}
+
+		seen := make(map[string]bool, len(step.DependencyIds))
+		for _, parentStepId := range step.DependencyIds {
+			if seen[parentStepId] {
+				report(LintDuplicateDependency, step.StepId, fmt.Sprintf("dependency %s is listed more than once", parentStepId))
+			}
+			seen[parentStepId] = true
+		}
+
+		// A direct dependency is redundant if another direct dependency already depends on it
+		for _, parentStepId := range getSortedKeys(seen) {
+			for _, otherParentId := range getSortedKeys(seen) {
+				if otherParentId == parentStepId {
+					continue
+				}
+				if getTransitiveDependencies(stepsByIdMap, otherParentId)[parentStepId] {
+					report(LintRedundantDependency, step.StepId,
+						fmt.Sprintf("dependency %s is already implied by dependency %s", parentStepId, otherParentId))
+					break
+				}
+			}
+		}
+
+		ratio := config[LintPrecedenceInversion].Ratio
+		for _, parentStepId := range getSortedKeys(seen) {
+			parent := stepsByIdMap[parentStepId]
+			if ratio > 0 && step.Precedence/ratio >= parent.Precedence {
+				report(LintPrecedenceInversion, step.StepId,
+					fmt.Sprintf("step (precedence %d) depends on %s (precedence %d), which may hold it back", step.Precedence, parentStepId, parent.Precedence))
+			}
+		}
+	}
+
+	return findings
+}
+
+// Returns the names of all known lint rules, sorted
+func LintRuleNames() []string {
+
+	rules := make([]string, 0)
+	for rule := range DefaultLintConfig() {
+		rules = append(rules, rule)
+	}
+	sort.Strings(rules)
+
+	return rules
+}
@@ -0,0 +1,110 @@
+package scheduler
+
+import (
+	"testing"
+)
+
+func TestCorrectlyLintsJobs(t *testing.T) {
+	var tests = []struct {
+		yamlInput     string
+		expectedRules []string
+	}{
+		{basicWithDependenciesInput, []string{}},
+		{oneStepInput, []string{}},
+		{lintHygieneInput, []string{
+			LintUntrimmedStepName,
+			LintDuplicateDependency,
+			LintRedundantDependency,
+			LintPrecedenceInversion,
+			LintIsolatedStep,
+		}},
+	}
+
+	for i, testCase := range tests {
+		findings, lintErr := LintUserJob(testCase.yamlInput, DefaultLintConfig())
+		if lintErr != nil {
+			t.Errorf("test %d: expected success, got error: %s", i, lintErr.Error())
+		}
+
+		rules := make([]string, len(findings))
+		for j, finding := range findings {
+			rules[j] = finding.Rule
+		}
+		if !areStringSlicesEqual(rules, testCase.expectedRules) {
+			t.Errorf("test %d: did not get equal lint rules, got: %v", i, findings)
+		}
+	}
+}
+
+func TestLintRulesAreConfigurable(t *testing.T) {
+	config, configErr := ParseLintConfig(`
+isolated-step:
+  severity: off
+precedence-inversion:
+  severity: error
+  ratio: 1000
+duplicate-dependency:
+  severity: error
+`)
+	if configErr != nil {
+		t.Fatalf("expected success, got error: %s", configErr.Error())
+	}
+
+	findings, lintErr := LintUserJob(lintHygieneInput, config)
+	if lintErr != nil {
+		t.Fatalf("expected success, got error: %s", lintErr.Error())
+	}
+
+	expectedSeverities := map[string]string{
+		LintUntrimmedStepName:   SeverityWarning,
+		LintDuplicateDependency: SeverityError,
+		LintRedundantDependency: SeverityWarning,
+	}
+	if len(findings) != len(expectedSeverities) {
+		t.Errorf("expected %d findings, got: %v", len(expectedSeverities), findings)
+	}
+	for _, finding := range findings {
+		if expectedSeverities[finding.Rule] != finding.Severity {
+			t.Errorf("unexpected finding: %s", finding.String())
+		}
+	}
+}
+
+func TestCorrectlyRejectsInvalidLintConfigs(t *testing.T) {
+	var tests = []struct {
+		configInput string
+	}{
+		{"not-a-rule:\n  severity: error\n"},
+		{"isolated-step:\n  severity: loud\n"},
+		{"precedence-inversion:\n  ratio: -5\n"},
+		{nonYamlStringInput},
+	}
+
+	for i, testCase := range tests {
+		if _, configErr := ParseLintConfig(testCase.configInput); configErr == nil {
+			t.Errorf("test %d: should have received error, did not", i)
+		}
+	}
+
+	if _, lintErr := LintUserJob(circularDependenciesInput, DefaultLintConfig()); lintErr == nil {
+		t.Errorf("should have received error for circular job, did not")
+	}
+}
+
+// Valid, but with one of each lint problem
+const lintHygieneInput string = `
+- step: " prepare database "
+  dependencies: []
+  precedence: 10
+- step: "create user 1"
+  dependencies: ["prepare database", "prepare database"]
+  precedence: 50
+- step: "create user 2"
+  dependencies: ["prepare database", "create user 1"]
+  precedence: 50
+- step: "send launch email"
+  dependencies: ["prepare database"]
+  precedence: 1000
+- step: "rotate logs"
+  precedence: 20
+`
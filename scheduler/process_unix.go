@@ -0,0 +1,21 @@
+//go:build !windows
+
+package scheduler
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// Starts the command in a new process group, so it and anything it spawns can be killed together
+func setProcessGroup(command *exec.Cmd) {
+	command.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// Kills every process in the command's process group
+func killProcessGroup(command *exec.Cmd) {
+	// A negative PID signals the whole group, whose ID is the leader's PID
+	if killErr := syscall.Kill(-command.Process.Pid, syscall.SIGKILL); killErr != nil {
+		command.Process.Kill()
+	}
+}
@@ -0,0 +1,14 @@
+//go:build windows
+
+package scheduler
+
+import (
+	"os/exec"
+)
+
+// Windows has no process groups to signal, so only the command itself is killed
+func setProcessGroup(command *exec.Cmd) {}
+
+func killProcessGroup(command *exec.Cmd) {
+	command.Process.Kill()
+}
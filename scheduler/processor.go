@@ -0,0 +1,222 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// This function is the primary interface boundary for the project. It takes
+// in a string of Yaml and returns an array of strings ready to be put in the user's
+// out file. Everything that's testable can be tested at this boundary.
+// The logic outside of the code is primarily os / io utilities (files, flags, sys codes)
+// Processing is bounded by the ResourceLimits in opts, including the timeout.
+func ProcessUserJob(yamlStr string, opts ...Option) ([]string, error) {
+
+	ctx, cancel := NewProcessingContext(getOptions(opts).limits)
+	defer cancel()
+
+	return ProcessUserJobWithContext(ctx, yamlStr, opts...)
+}
+
+// Like ProcessUserJob, but gives up with the context's error once ctx is done
+func ProcessUserJobWithContext(ctx context.Context, yamlStr string, opts ...Option) ([]string, error) {
+
+	job, jobErr := getValidatedJob(yamlStr, opts...)
+	if jobErr != nil {
+		return make([]string, 0), jobErr
+	}
+
+	return scheduleSteps(ctx, job.Steps)
+}
+
+// Parses and validates the user's YAML, returning the linked dependency graph ready for scheduling
+func getValidatedJob(yamlStr string, opts ...Option) (*Job, error) {
+
+	// 1. Feed the string into Go YAML parser to get back an InputJob
+	inputJob, parseErr := Parse(yamlStr, opts...)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	// 2. Link and validate inputJob.Steps into the job's dependency graph
+	return Validate(inputJob)
+}
+
+// Cycles through the dependency graph, pulling the next available step until none remain.
+// Steps already marked allDepsClear (e.g. completed in a previous run) are not emitted.
+func scheduleSteps(ctx context.Context, stepsByIdSlice []*Step) ([]string, error) {
+
+	output := make([]string, 0)
+
+	remaining := 0
+	for _, step := range stepsByIdSlice {
+		if !step.allDepsClear {
+			remaining++
+		}
+	}
+
+	// Cycle through our tree until we get nothing else
+	for {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return output, fmt.Errorf("scheduling stopped: %w", ctxErr)
+		}
+
+		nextStep := getNextAvailableStep(stepsByIdSlice)
+		//Actually update my nextStep node
+
+		if nextStep == nil {
+			if len(output) != remaining { // Possible circular dependency
+				return output, &CycleError{StepIds: getUnscheduledStepIds(stepsByIdSlice)}
+			}
+			break
+		}
+
+		output = append(output, nextStep.StepId)
+	}
+
+	return output, nil
+}
+
+// Returns the sorted IDs of the steps that the scheduler hasn't emitted
+func getUnscheduledStepIds(stepsByIdSlice []*Step) []string {
+
+	stepIds := make([]string, 0)
+	for _, step := range stepsByIdSlice {
+		if !step.allDepsClear {
+			stepIds = append(stepIds, step.StepId)
+		}
+	}
+	sort.Strings(stepIds)
+
+	return stepIds
+}
+
+// Return a map which is keyed by the id of the step, given an array of user inputs
+func getStepsByIdSlice(inputSteps []*InputJobStep) ([]*Step, error) {
+
+	output := make([]*Step, 0)
+	stepsByIdMap := make(map[string]*Step)
+	stepIdsBySkeleton := make(map[string]string)
+	for i, inputStep := range inputSteps {
+		if inputStep == nil {
+			return output, &ValidationError{Message: fmt.Sprintf("step %d is empty", i+1)}
+		}
+
+		validationErr := inputStep.ValidateInputStep()
+		if validationErr != nil {
+			return output, &ValidationError{
+				StepIds: []string{strings.TrimSpace(inputStep.StepName)},
+				Message: "validation error received: " + validationErr.Error(),
+			}
+		}
+
+		jobStep := inputStep.GetStep()
+
+		// Check if key already exists: if so, there's a dupe, which should return error
+		if _, isDuplicateStep := stepsByIdMap[jobStep.StepId]; isDuplicateStep {
+			return output, &ValidationError{
+				StepIds: []string{jobStep.StepId},
+				Message: fmt.Sprintf("duplicate key detected: %s", jobStep.StepId),
+			}
+		}
+
+		// Distinct IDs that render the same are as good as duplicates to whoever reads the output
+		skeleton := getConfusableSkeleton(jobStep.StepId)
+		if confusableStepId, isConfusable := stepIdsBySkeleton[skeleton]; isConfusable {
+			return output, &ValidationError{
+				StepIds: []string{confusableStepId, jobStep.StepId},
+				Message: fmt.Sprintf("visually confusable keys detected: %q and %q", confusableStepId, jobStep.StepId),
+			}
+		}
+		stepIdsBySkeleton[skeleton] = jobStep.StepId
+
+		stepsByIdMap[jobStep.StepId] = jobStep
+		output = append(output, jobStep)
+	}
+
+	// Now we loop over our dependecyIds (array of strings), for each step, and assign a pointer
+	// to that actual parent node in our depsToClear[parentStepId] map. If a node cannot be found,
+	// that means that the input dependency string does not match any actual steps.
+	for i, step := range output {
+		for _, parentStepId := range step.DependencyIds {
+			if parent, parentOk := stepsByIdMap[parentStepId]; parentOk {
+				stepId := step.StepId
+				stepsByIdMap[stepId].depsToClear[parentStepId] = parent
+				output[i] = stepsByIdMap[stepId]
+			} else {
+				return output, getInvalidDependencyError(output, parentStepId)
+			}
+		}
+	}
+
+	return output, nil
+}
+
+// Builds the error for a dependency that doesn't match any step, listing every step that
+// references it and the existing step IDs it was most likely meant to be
+func getInvalidDependencyError(steps []*Step, missingStepId string) *ValidationError {
+
+	stepIds := make([]string, len(steps))
+	referencedBy := make([]string, 0)
+	for i, step := range steps {
+		stepIds[i] = step.StepId
+		if containsString(step.DependencyIds, missingStepId) {
+			referencedBy = append(referencedBy, step.StepId)
+		}
+	}
+
+	errStr := fmt.Sprintf("invalid dependency specified: %s (referenced by: %s)", missingStepId, strings.Join(referencedBy, ", "))
+
+	suggestions := getSuggestedStepIds(missingStepId, stepIds, 3)
+	if len(suggestions) > 0 {
+		errStr += fmt.Sprintf("; did you mean: %s?", strings.Join(suggestions, ", "))
+	}
+
+	return &ValidationError{StepIds: referencedBy, Message: errStr}
+}
+
+func getNextAvailableStep(stepsByIdSlice []*Step) *Step {
+
+	possibleNodes := make([]*Step, 0)
+	for _, step := range stepsByIdSlice {
+		if len(step.depsToClear) == 0 && !step.allDepsClear {
+			possibleNodes = append(possibleNodes, step)
+		}
+	}
+
+	// We're done.
+	if len(possibleNodes) == 0 {
+		return nil
+	}
+
+	sort.Slice(possibleNodes, func(i, j int) bool {
+		return runsBefore(possibleNodes[i], possibleNodes[j])
+	})
+
+	nodeToReturn := possibleNodes[0]
+	for _, node := range stepsByIdSlice {
+		newDeps := make(map[string]*Step, 0)
+		for _, depToClear := range node.depsToClear {
+			if depToClear.StepId == nodeToReturn.StepId {
+				continue
+			}
+			newDeps[depToClear.StepId] = depToClear
+		}
+		node.depsToClear = newDeps
+	}
+
+	nodeToReturn.allDepsClear = true
+
+	return nodeToReturn
+}
+
+// Whether step a should be picked before step b when both are ready to run:
+// higher precedence first, then lexicographically by StepId
+func runsBefore(a *Step, b *Step) bool {
+	if a.Precedence != b.Precedence {
+		return a.Precedence > b.Precedence
+	}
+	return a.StepId < b.StepId
+}
@@ -1,6 +1,7 @@
-package main
+package scheduler
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -22,6 +23,8 @@ func TestCorrectlyProcessesValidInputs(t *testing.T) {
 		{complexWithDependenciesMultipleStepLeadingTrailingWhitespaceInput, complexWithDependenciesOutput},
 		{complexWithDependenciesForPrecedenceTestingInput, complexWithDependenciesOutput},
 		{complexValidInput, complexValidOutput},
+		{decomposedUnicodeDependencyInput, decomposedUnicodeDependencyOutput},
+		{basicWithDependenciesCrlfInput, basicWithDependenciesOutput},
 	}
 
 	for i, testCase := range tests {
@@ -69,6 +72,22 @@ func TestCorrectlyRejectsInvalidInputs(t *testing.T) {
 		{singleEmptyDependency},
 		{multipleInvalidDependency},
 		{multipleEmptyDependency},
+		{carriageReturnInNameInput},
+		{tabInNameInput},
+		{nulInNameInput},
+		{lineSeparatorInNameInput},
+		{zeroWidthSpaceInNameInput},
+		{zeroWidthSpaceInDependencyInput},
+		{confusableHomoglyphNamesInput},
+		{confusableFullwidthNamesInput},
+		{nullStepInput},
+		{runWithoutCommandInput},
+		{runWithInvalidEnvInput},
+		{negativeRetriesInput},
+		{invalidRetryBackoffInput},
+		{zeroTimeoutInput},
+		{invalidInputPatternInput},
+		{outputOutsideWorkingDirInput},
 	}
 
 	for i, testCase := range tests {
@@ -1091,6 +1110,143 @@ const multipleEmptyDependency string = `
   precedence: 100
 `
 
+// "café" spelled with a precomposed é in the step, and e + combining acute in the dependency
+const decomposedUnicodeDependencyInput string = `
+- step: "open caf\u00e9"
+  dependencies: []
+  precedence: 50
+- step: "serve coffee"
+  dependencies: ["open cafe\u0301"]
+  precedence: 100
+`
+
+var decomposedUnicodeDependencyOutput = []string{
+	"open caf\u00e9",
+	"serve coffee",
+}
+
+// basicWithDependenciesInput as saved by a Windows editor
+var basicWithDependenciesCrlfInput = strings.ReplaceAll(basicWithDependenciesInput, "\n", "\r\n")
+
+const carriageReturnInNameInput string = `
+- step: "prepare\rdatabase"
+  dependencies: []
+  precedence: 50
+`
+
+const tabInNameInput string = `
+- step: "prepare\tdatabase"
+  dependencies: []
+  precedence: 50
+`
+
+const nulInNameInput string = `
+- step: "prepare database\0"
+  dependencies: []
+  precedence: 50
+`
+
+const lineSeparatorInNameInput string = `
+- step: "prepare\u2028database"
+  dependencies: []
+  precedence: 50
+`
+
+const zeroWidthSpaceInNameInput string = `
+- step: "prepare\u200bdatabase"
+  dependencies: []
+  precedence: 50
+`
+
+const zeroWidthSpaceInDependencyInput string = `
+- step: "prepare database"
+  dependencies: []
+  precedence: 50
+- step: "create user"
+  dependencies: ["prepare\u200b database"]
+  precedence: 50
+`
+
+// The second "prepare" starts with a Cyrillic "р"
+const confusableHomoglyphNamesInput string = `
+- step: "prepare database"
+  dependencies: []
+  precedence: 50
+- step: "\u0440repare database"
+  dependencies: []
+  precedence: 50
+`
+
+const confusableFullwidthNamesInput string = `
+- step: "db"
+  dependencies: []
+  precedence: 50
+- step: "\uff44\uff42"
+  dependencies: []
+  precedence: 50
+`
+
+const nullStepInput string = `
+- step: "prepare database"
+  dependencies: []
+  precedence: 50
+- ~
+`
+
+const runWithoutCommandInput string = `
+- step: "prepare database"
+  dependencies: []
+  precedence: 50
+  run:
+    args: ["--verbose"]
+`
+
+const negativeRetriesInput string = `
+- step: "prepare database"
+  dependencies: []
+  precedence: 50
+  retries: -1
+`
+
+const invalidRetryBackoffInput string = `
+- step: "prepare database"
+  dependencies: []
+  precedence: 50
+  retries: 3
+  retry_backoff: soon
+`
+
+const invalidInputPatternInput string = `
+- step: "prepare database"
+  dependencies: []
+  precedence: 50
+  inputs: ["schema/[.sql"]
+`
+
+const outputOutsideWorkingDirInput string = `
+- step: "prepare database"
+  dependencies: []
+  precedence: 50
+  outputs: ["../schema.sql"]
+`
+
+const zeroTimeoutInput string = `
+- step: "prepare database"
+  dependencies: []
+  precedence: 50
+  timeout: 0s
+`
+
+const runWithInvalidEnvInput string = `
+- step: "prepare database"
+  dependencies: []
+  precedence: 50
+  run:
+    command: "sh"
+    env:
+      "DB=NAME": users
+`
+
 /**
 The rules:
 
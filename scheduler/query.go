@@ -0,0 +1,348 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Which way a StepRelations query walked the graph
+const (
+	RelationDependencies = "dependencies"
+	RelationDependents   = "dependents"
+)
+
+// The dependencies or dependents of one step, as found by the deps and rdeps commands
+type StepRelations struct {
+	StepId     string   `json:"step"`
+	Relation   string   `json:"relation"`   // RelationDependencies or RelationDependents
+	Transitive bool     `json:"transitive"` // Whether indirect relations are included
+	StepIds    []string `json:"steps"`      // Sorted by step ID
+}
+
+func (relations *StepRelations) String() string {
+
+	kind := "direct"
+	if relations.Transitive {
+		kind = "transitive"
+	}
+
+	if len(relations.StepIds) == 0 {
+		return fmt.Sprintf("%s has no %s\n", relations.StepId, relations.Relation)
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("%s has %d %s %s:\n", relations.StepId, len(relations.StepIds), kind, relations.Relation))
+	for _, stepId := range relations.StepIds {
+		builder.WriteString("  " + stepId + "\n")
+	}
+
+	return builder.String()
+}
+
+// The dependency chains between two steps, as found by the path command
+type DependencyPaths struct {
+	FromStepId string     `json:"from"`
+	ToStepId   string     `json:"to"`
+	Shortest   bool       `json:"shortest"`  // Whether only the shortest paths were kept
+	Paths      [][]string `json:"paths"`     // Each in run order, from the dependency to its dependent
+	Truncated  bool       `json:"truncated"` // Whether there were more paths than were asked for
+}
+
+func (dependencyPaths *DependencyPaths) String() string {
+
+	if len(dependencyPaths.Paths) == 0 {
+		return fmt.Sprintf("%s and %s don't depend on each other\n", dependencyPaths.FromStepId, dependencyPaths.ToStepId)
+	}
+
+	kind := "path(s)"
+	if dependencyPaths.Shortest {
+		kind = "shortest path(s)"
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("%d %s between %s and %s:\n", len(dependencyPaths.Paths), kind, dependencyPaths.FromStepId, dependencyPaths.ToStepId))
+	for _, path := range dependencyPaths.Paths {
+		builder.WriteString("  " + strings.Join(path, " -> ") + "\n")
+	}
+	if dependencyPaths.Truncated {
+		builder.WriteString(fmt.Sprintf("stopped after %d path(s), there are more\n", len(dependencyPaths.Paths)))
+	}
+
+	return builder.String()
+}
+
+// Parses and validates the user's job and lists the dependencies of the given step
+func FindUserJobDependencies(yamlStr string, stepId string, transitive bool, opts ...Option) (*StepRelations, error) {
+
+	job, jobErr := getValidatedJob(yamlStr, opts...)
+	if jobErr != nil {
+		return nil, jobErr
+	}
+
+	return GetStepRelations(job, stepId, RelationDependencies, transitive)
+}
+
+// Parses and validates the user's job and lists the dependents of the given step
+func FindUserJobDependents(yamlStr string, stepId string, transitive bool, opts ...Option) (*StepRelations, error) {
+
+	job, jobErr := getValidatedJob(yamlStr, opts...)
+	if jobErr != nil {
+		return nil, jobErr
+	}
+
+	return GetStepRelations(job, stepId, RelationDependents, transitive)
+}
+
+// Parses and validates the user's job and finds the dependency paths between two steps, giving
+// up once the processing timeout in opts runs out
+func FindUserJobPaths(yamlStr string, fromStepId string, toStepId string, shortest bool, maxPaths int, opts ...Option) (*DependencyPaths, error) {
+
+	ctx, cancel := NewProcessingContext(getOptions(opts).limits)
+	defer cancel()
+
+	job, jobErr := getValidatedJob(yamlStr, opts...)
+	if jobErr != nil {
+		return nil, jobErr
+	}
+
+	return GetDependencyPaths(ctx, job, fromStepId, toStepId, shortest, maxPaths)
+}
+
+// Lists the steps that stepId depends on or that depend on it, per relation. Unlike scheduling,
+// this works on a job with cycles.
+func GetStepRelations(job *Job, stepId string, relation string, transitive bool) (*StepRelations, error) {
+
+	graph := getStepGraph(job)
+	normalizedId, lookupErr := graph.lookupStepId(stepId)
+	if lookupErr != nil {
+		return nil, lookupErr
+	}
+
+	edges := graph.dependencies
+	switch relation {
+	case RelationDependencies:
+	case RelationDependents:
+		edges = graph.dependents
+	default:
+		return nil, fmt.Errorf("unknown relation: %s", relation)
+	}
+
+	relations := &StepRelations{StepId: normalizedId, Relation: relation, Transitive: transitive}
+	if !transitive {
+		relations.StepIds = append(make([]string, 0), edges[normalizedId]...)
+		return relations, nil
+	}
+
+	reachable := getReachableStepIds(edges, normalizedId)
+	delete(reachable, normalizedId) // Only possible through a cycle
+	relations.StepIds = getSortedKeys(reachable)
+
+	return relations, nil
+}
+
+// Finds the dependency paths between two steps, in whichever direction they depend on each other,
+// with each path starting at the dependency. Paths are listed in step ID order, and only the
+// shortest are kept if shortest is set. At most maxPaths are returned, or all of them if it's 0.
+// The two steps must be different.
+func GetDependencyPaths(ctx context.Context, job *Job, fromStepId string, toStepId string, shortest bool, maxPaths int) (*DependencyPaths, error) {
+
+	graph := getStepGraph(job)
+	normalizedFromId, fromErr := graph.lookupStepId(fromStepId)
+	if fromErr != nil {
+		return nil, fromErr
+	}
+	normalizedToId, toErr := graph.lookupStepId(toStepId)
+	if toErr != nil {
+		return nil, toErr
+	}
+	// A lone step would otherwise come back as a one-step path, as if it depended on itself
+	if normalizedFromId == normalizedToId {
+		errStr := fmt.Sprintf("path needs two different steps, got %s twice", normalizedFromId)
+		return nil, &ValidationError{StepIds: []string{normalizedFromId}, Message: errStr}
+	}
+
+	dependencyPaths := &DependencyPaths{
+		FromStepId: normalizedFromId,
+		ToStepId:   normalizedToId,
+		Shortest:   shortest,
+		Paths:      make([][]string, 0),
+	}
+
+	finder := &pathFinder{ctx: ctx, graph: graph, shortest: shortest, maxPaths: maxPaths}
+	if finderErr := finder.find(normalizedFromId, normalizedToId); finderErr != nil {
+		return nil, finderErr
+	}
+	if len(finder.paths) == 0 {
+		if finderErr := finder.find(normalizedToId, normalizedFromId); finderErr != nil {
+			return nil, finderErr
+		}
+	}
+
+	dependencyPaths.Paths = append(dependencyPaths.Paths, finder.paths...)
+	dependencyPaths.Truncated = finder.truncated
+
+	return dependencyPaths, nil
+}
+
+// Both directions of a job's dependency edges, with duplicate dependencies removed and each
+// step's neighbours sorted by ID
+type stepGraph struct {
+	stepsByIdMap map[string]*Step
+	dependencies map[string][]string
+	dependents   map[string][]string
+}
+
+func getStepGraph(job *Job) *stepGraph {
+
+	graph := &stepGraph{
+		stepsByIdMap: getStepsByIdMap(job.Steps),
+		dependencies: make(map[string][]string, len(job.Steps)),
+		dependents:   make(map[string][]string, len(job.Steps)),
+	}
+
+	for _, step := range job.Steps {
+		graph.dependencies[step.StepId] = getSortedKeys(getDependencySet(step))
+		for _, parentStepId := range graph.dependencies[step.StepId] {
+			graph.dependents[parentStepId] = append(graph.dependents[parentStepId], step.StepId)
+		}
+	}
+	for _, dependentIds := range graph.dependents {
+		sort.Strings(dependentIds)
+	}
+
+	return graph
+}
+
+// Normalizes a step ID given by the user, and checks that it's in the job
+func (graph *stepGraph) lookupStepId(stepId string) (string, error) {
+
+	normalizedId := NormalizeStepId(stepId)
+	if _, stepOk := graph.stepsByIdMap[normalizedId]; stepOk {
+		return normalizedId, nil
+	}
+
+	errStr := fmt.Sprintf("step not found in job: %s", normalizedId)
+	suggestions := getSuggestedStepIds(normalizedId, getSortedStepIds(graph.stepsByIdMap), 3)
+	if len(suggestions) > 0 {
+		errStr += fmt.Sprintf("; did you mean: %s?", strings.Join(suggestions, ", "))
+	}
+
+	return "", &ValidationError{StepIds: []string{normalizedId}, Message: errStr}
+}
+
+// Returns the set of steps reachable from stepId along edges, not including stepId unless it's
+// on a cycle
+func getReachableStepIds(edges map[string][]string, stepId string) map[string]bool {
+
+	visited := make(map[string]bool)
+	toVisit := []string{stepId}
+	for len(toVisit) > 0 {
+		currentId := toVisit[len(toVisit)-1]
+		toVisit = toVisit[:len(toVisit)-1]
+
+		for _, nextStepId := range edges[currentId] {
+			if !visited[nextStepId] {
+				visited[nextStepId] = true
+				toVisit = append(toVisit, nextStepId)
+			}
+		}
+	}
+
+	return visited
+}
+
+// Walks the dependents from one step towards another, collecting every path between them
+type pathFinder struct {
+	ctx      context.Context
+	graph    *stepGraph
+	shortest bool
+	maxPaths int
+
+	targetId   string
+	distanceTo map[string]int // Fewest edges from each step to the target, for steps that reach it at all
+	path       []string
+	onPath     map[string]bool
+	paths      [][]string
+	truncated  bool
+}
+
+// Finds the paths from dependency fromStepId to its transitive dependent toStepId, if it is one
+func (finder *pathFinder) find(fromStepId string, toStepId string) error {
+
+	finder.targetId = toStepId
+	finder.distanceTo = finder.getDistancesTo(toStepId)
+	if _, reachesTarget := finder.distanceTo[fromStepId]; !reachesTarget {
+		return nil
+	}
+
+	finder.path = make([]string, 0, finder.distanceTo[fromStepId]+1)
+	finder.onPath = make(map[string]bool)
+
+	return finder.walk(fromStepId)
+}
+
+// Works backwards from the target along dependencies, so that the walk forwards never enters a
+// step that can't reach it, and the shortest paths can be followed one edge at a time
+func (finder *pathFinder) getDistancesTo(toStepId string) map[string]int {
+
+	distanceTo := map[string]int{toStepId: 0}
+	toVisit := []string{toStepId}
+	for len(toVisit) > 0 {
+		currentId := toVisit[0]
+		toVisit = toVisit[1:]
+
+		for _, parentStepId := range finder.graph.dependencies[currentId] {
+			if _, seen := distanceTo[parentStepId]; !seen {
+				distanceTo[parentStepId] = distanceTo[currentId] + 1
+				toVisit = append(toVisit, parentStepId)
+			}
+		}
+	}
+
+	return distanceTo
+}
+
+func (finder *pathFinder) walk(stepId string) error {
+
+	if ctxErr := finder.ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("path search stopped: %w", ctxErr)
+	}
+
+	finder.path = append(finder.path, stepId)
+	finder.onPath[stepId] = true
+	defer func() {
+		finder.path = finder.path[:len(finder.path)-1]
+		finder.onPath[stepId] = false
+	}()
+
+	if stepId == finder.targetId {
+		if finder.maxPaths > 0 && len(finder.paths) == finder.maxPaths {
+			finder.truncated = true
+			return nil
+		}
+		finder.paths = append(finder.paths, append(make([]string, 0, len(finder.path)), finder.path...))
+		return nil
+	}
+
+	for _, dependentId := range finder.graph.dependents[stepId] {
+		if finder.truncated {
+			return nil
+		}
+
+		distance, reachesTarget := finder.distanceTo[dependentId]
+		if !reachesTarget || finder.onPath[dependentId] {
+			continue
+		}
+		if finder.shortest && distance != finder.distanceTo[stepId]-1 {
+			continue
+		}
+
+		if walkErr := finder.walk(dependentId); walkErr != nil {
+			return walkErr
+		}
+	}
+
+	return nil
+}
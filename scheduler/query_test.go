@@ -0,0 +1,135 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCorrectlyFindsStepRelations(t *testing.T) {
+	var tests = []struct {
+		yamlInput  string
+		stepId     string
+		relation   string
+		transitive bool
+		expected   []string
+	}{
+		{oneStepInput, "prepare database", RelationDependencies, true, []string{}},
+		{basicWithDependenciesInput, "prepare database", RelationDependents, false, []string{"create user 1", "create user 2"}},
+		{basicWithDependenciesInput, "prepare database", RelationDependents, true, []string{"create user 1", "create user 2", "create user 3", "create user 4"}},
+		{basicWithDependenciesInput, " create user 3 ", RelationDependencies, false, []string{"create user 4"}},
+		{basicWithDependenciesInput, " create user 3 ", RelationDependencies, true, []string{"create user 2", "create user 4", "prepare database"}},
+		{complexWithDependenciesInput, "enable cdn distribution", RelationDependencies, false, []string{"create bucket", "deploy api gateway", "deploy database", "enable dns records"}},
+		{complexWithDependenciesInput, "enable cdn distribution", RelationDependencies, true, []string{"create bucket", "deploy api gateway", "deploy database", "deploy lambda function", "enable dns records"}},
+		{complexWithDependenciesInput, "enable cdn distribution", RelationDependents, true, []string{}},
+		{circularDependenciesInput, "deploy lambda function", RelationDependencies, true, []string{"deploy api gateway", "enable dns records"}},
+		{circularDependenciesInput, "enable dns records", RelationDependents, true, []string{"deploy api gateway", "deploy lambda function", "enable cdn distribution"}},
+	}
+
+	for i, testCase := range tests {
+		findRelations := FindUserJobDependencies
+		if testCase.relation == RelationDependents {
+			findRelations = FindUserJobDependents
+		}
+
+		relations, findErr := findRelations(testCase.yamlInput, testCase.stepId, testCase.transitive)
+		if findErr != nil {
+			t.Errorf("test %d: expected success, got error: %s", i, findErr.Error())
+			continue
+		}
+
+		if !areStringSlicesEqual(relations.StepIds, testCase.expected) {
+			t.Errorf("test %d: expected %s %v, got %v", i, testCase.relation, testCase.expected, relations.StepIds)
+		}
+		if relations.StepId != strings.TrimSpace(testCase.stepId) {
+			t.Errorf("test %d: expected the step ID to be normalized, got %q", i, relations.StepId)
+		}
+	}
+}
+
+func TestCorrectlyFindsDependencyPaths(t *testing.T) {
+	var tests = []struct {
+		yamlInput  string
+		fromStepId string
+		toStepId   string
+		shortest   bool
+		maxPaths   int
+		expected   []string
+		truncated  bool
+	}{
+		{complexWithDependenciesInput, "enable dns records", "enable cdn distribution", false, 0, []string{
+			"enable dns records -> deploy api gateway -> enable cdn distribution",
+			"enable dns records -> enable cdn distribution",
+		}, false},
+		{complexWithDependenciesInput, "enable cdn distribution", "enable dns records", true, 0, []string{
+			"enable dns records -> enable cdn distribution",
+		}, false},
+		{complexWithDependenciesInput, "enable dns records", "enable cdn distribution", false, 1, []string{
+			"enable dns records -> deploy api gateway -> enable cdn distribution",
+		}, true},
+		{complexWithDependenciesInput, "create bucket", "deploy database", false, 0, []string{}, false},
+		{basicWithDependenciesInput, "prepare database", "create user 3", false, 0, []string{
+			"prepare database -> create user 2 -> create user 4 -> create user 3",
+		}, false},
+		{circularDependenciesInput, "enable dns records", "enable cdn distribution", false, 0, []string{
+			"enable dns records -> deploy api gateway -> enable cdn distribution",
+			"enable dns records -> enable cdn distribution",
+		}, false},
+	}
+
+	for i, testCase := range tests {
+		dependencyPaths, findErr := FindUserJobPaths(testCase.yamlInput, testCase.fromStepId, testCase.toStepId, testCase.shortest, testCase.maxPaths)
+		if findErr != nil {
+			t.Errorf("test %d: expected success, got error: %s", i, findErr.Error())
+			continue
+		}
+
+		paths := make([]string, len(dependencyPaths.Paths))
+		for j, path := range dependencyPaths.Paths {
+			paths[j] = strings.Join(path, " -> ")
+		}
+		if !areStringSlicesEqual(paths, testCase.expected) {
+			t.Errorf("test %d: expected paths %v, got %v", i, testCase.expected, paths)
+		}
+		if dependencyPaths.Truncated != testCase.truncated {
+			t.Errorf("test %d: expected truncated to be %t", i, testCase.truncated)
+		}
+	}
+}
+
+func TestPathsRejectIdenticalSteps(t *testing.T) {
+	// The IDs are compared after normalizing, so differently spaced copies are still the same step
+	_, pathsErr := FindUserJobPaths(basicWithDependenciesInput, "prepare database", "  prepare database ", false, 0)
+
+	var validationErr *ValidationError
+	if !errors.As(pathsErr, &validationErr) || !areStringSlicesEqual(validationErr.StepIds, []string{"prepare database"}) {
+		t.Errorf("expected a validation error for prepare database, got %v", pathsErr)
+	}
+}
+
+func TestQueriesRejectUnknownSteps(t *testing.T) {
+	_, dependenciesErr := FindUserJobDependencies(basicWithDependenciesInput, "create user 9", false)
+	_, pathsErr := FindUserJobPaths(basicWithDependenciesInput, "prepare database", "create user 9", false, 0)
+
+	for i, queryErr := range []error{dependenciesErr, pathsErr} {
+		var validationErr *ValidationError
+		if !errors.As(queryErr, &validationErr) || !strings.Contains(queryErr.Error(), "did you mean: create user 1") {
+			t.Errorf("test %d: expected a validation error with suggestions, got %v", i, queryErr)
+		}
+	}
+}
+
+func TestPathSearchStopsWhenCancelled(t *testing.T) {
+	job, jobErr := getValidatedJob(complexWithDependenciesInput)
+	if jobErr != nil {
+		t.Fatalf("received unexpected error: %s", jobErr.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, pathsErr := GetDependencyPaths(ctx, job, "enable dns records", "enable cdn distribution", false, 0); !errors.Is(pathsErr, context.Canceled) {
+		t.Errorf("expected the search to be cancelled, got %v", pathsErr)
+	}
+}
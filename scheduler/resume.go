@@ -0,0 +1,88 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+)
+
+// Like ProcessUserJob, but for resuming a pipeline that died partway through. See Resume.
+func ResumeUserJob(yamlStr string, completedIds []string, opts ...Option) ([]string, []string, error) {
+
+	job, jobErr := getValidatedJob(yamlStr, opts...)
+	if jobErr != nil {
+		return make([]string, 0), make([]string, 0), jobErr
+	}
+
+	ctx, cancel := NewProcessingContext(getOptions(opts).limits)
+	defer cancel()
+
+	return Resume(ctx, job, completedIds)
+}
+
+// Like Schedule, but steps listed in completedIds are treated as already run: they're cleared out
+// of every depsToClear map and left out of the returned ordering. Completed IDs that don't exist
+// in the job come back as warnings rather than errors, since the job may have been edited since
+// the original run.
+func Resume(ctx context.Context, job *Job, completedIds []string) ([]string, []string, error) {
+
+	stepsByIdSlice := job.getSchedulingGraph()
+
+	warnings, completedErr := markStepsCompleted(stepsByIdSlice, completedIds)
+	if completedErr != nil {
+		return make([]string, 0), warnings, fmt.Errorf("could not resume job: %w", completedErr)
+	}
+
+	output, scheduleErr := scheduleSteps(ctx, stepsByIdSlice)
+	return output, warnings, scheduleErr
+}
+
+// Marks each of the completedIds as already cleared in the graph. A completed step whose own
+// dependencies aren't also completed is an error, as that ordering could never have happened.
+// Returns a warning for each completed ID that doesn't match a step in the job.
+func markStepsCompleted(stepsByIdSlice []*Step, completedIds []string) ([]string, error) {
+
+	warnings := make([]string, 0)
+
+	stepsByIdMap := getStepsByIdMap(stepsByIdSlice)
+
+	completedMap := make(map[string]*Step)
+	for _, rawId := range completedIds {
+		completedId := NormalizeStepId(rawId)
+		if completedId == "" {
+			continue
+		}
+
+		step, stepOk := stepsByIdMap[completedId]
+		if !stepOk {
+			warnings = append(warnings, fmt.Sprintf("completed step not found in job: %s", completedId))
+			continue
+		}
+		completedMap[completedId] = step
+	}
+
+	// Check every completed step's parents before touching the graph, so that an error
+	// leaves the steps untouched. Sorted so the reported error is deterministic.
+	for _, completedId := range getSortedStepIds(completedMap) {
+		for _, parentStepId := range completedMap[completedId].DependencyIds {
+			if _, parentCompleted := completedMap[parentStepId]; !parentCompleted {
+				return warnings, &ValidationError{
+					StepIds: []string{completedId},
+					Message: fmt.Sprintf("completed step %s depends on %s, which is not marked completed", completedId, parentStepId),
+				}
+			}
+		}
+	}
+
+	for _, step := range stepsByIdSlice {
+		if _, isCompleted := completedMap[step.StepId]; isCompleted {
+			step.allDepsClear = true
+		}
+		for parentStepId := range step.depsToClear {
+			if _, parentCompleted := completedMap[parentStepId]; parentCompleted {
+				delete(step.depsToClear, parentStepId)
+			}
+		}
+	}
+
+	return warnings, nil
+}
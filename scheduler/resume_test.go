@@ -0,0 +1,77 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCorrectlyResumesFromCompletedSteps(t *testing.T) {
+	var tests = []struct {
+		yamlInput        string
+		completedIds     []string
+		correctOutput    []string
+		expectedWarnings int
+	}{
+		{basicWithDependenciesInput, []string{}, basicWithDependenciesOutput, 0},
+		{basicWithDependenciesInput, []string{"prepare database", "create user 2"}, []string{"create user 1", "create user 4", "create user 3"}, 0},
+		{basicWithDependenciesInput, []string{" prepare database ", "", "prepare database"}, basicWithDependenciesOutput[1:], 0},
+		{basicWithDependenciesInput, []string{"prepare database", "drop database"}, basicWithDependenciesOutput[1:], 1},
+		{basicWithDependenciesInput, basicWithDependenciesOutput, []string{}, 0},
+	}
+
+	for i, testCase := range tests {
+		output, warnings, outputErr := ResumeUserJob(testCase.yamlInput, testCase.completedIds)
+		if outputErr != nil {
+			t.Errorf("test %d: expected success, got error: %s", i, outputErr.Error())
+		}
+
+		if !areStringSlicesEqual(output, testCase.correctOutput) {
+			t.Errorf("test %d: did not get equal string arrays, got: %v", i, output)
+		}
+
+		if len(warnings) != testCase.expectedWarnings {
+			t.Errorf("test %d: expected %d warnings, got: %v", i, testCase.expectedWarnings, warnings)
+		}
+	}
+}
+
+func TestResumeLeavesJobReusable(t *testing.T) {
+	inputJob, _ := Parse(basicWithDependenciesInput)
+	job, _ := Validate(inputJob)
+
+	// Resuming shouldn't clear anything in the Job itself, so a full schedule afterwards is unaffected
+	resumed, _, resumeErr := Resume(context.Background(), job, []string{"prepare database", "create user 2"})
+	if resumeErr != nil {
+		t.Fatalf("received unexpected error: %s", resumeErr.Error())
+	}
+	if !areStringSlicesEqual(resumed, []string{"create user 1", "create user 4", "create user 3"}) {
+		t.Errorf("resumed ordering did not match, got %v", resumed)
+	}
+
+	output, scheduleErr := Schedule(context.Background(), job)
+	if scheduleErr != nil {
+		t.Fatalf("received unexpected error: %s", scheduleErr.Error())
+	}
+	if !areStringSlicesEqual(output, basicWithDependenciesOutput) {
+		t.Errorf("ordering after resuming did not match, got %v", output)
+	}
+}
+
+func TestCorrectlyRejectsInvalidCompletedSteps(t *testing.T) {
+	var tests = []struct {
+		yamlInput    string
+		completedIds []string
+	}{
+		{basicWithDependenciesInput, []string{"create user 1"}},
+		{basicWithDependenciesInput, []string{"prepare database", "create user 4"}},
+		{circularDependenciesInput, []string{"deploy database"}},
+		{emptyStringInput, []string{"deploy database"}},
+	}
+
+	for i, testCase := range tests {
+		_, _, outputErr := ResumeUserJob(testCase.yamlInput, testCase.completedIds)
+		if outputErr == nil {
+			t.Errorf("test %d: should have received error, did not", i)
+		}
+	}
+}
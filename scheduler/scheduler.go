@@ -0,0 +1,162 @@
+// Package scheduler works out the order to run a job's steps in, given each step's
+// dependencies and precedence. A job goes through three stages:
+//
+//	inputJob, parseErr := scheduler.Parse(yamlStr)         // YAML to raw, unvalidated steps
+//	job, validateErr := scheduler.Validate(inputJob)       // Job rules checked, dependency graph linked
+//	ordering, scheduleErr := scheduler.Schedule(ctx, job)  // Step IDs in the order they should run
+//
+// ProcessUserJob does all three in one call. Errors from each stage are a *ParseError,
+// *LimitError, *ValidationError or *CycleError, so callers can tell them apart with errors.As.
+// Resource limits are set with WithLimits and default to DefaultResourceLimits.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Configures Parse and the functions built on it
+type Option func(*options)
+
+type options struct {
+	limits    ResourceLimits
+	keepGoing bool
+	cacheDir  string
+}
+
+// Replaces the default resource limits
+func WithLimits(limits ResourceLimits) Option {
+	return func(opts *options) {
+		opts.limits = limits
+	}
+}
+
+// Keeps executing steps that don't depend on a failed one instead of stopping at the first
+// failure, like make -k
+func WithKeepGoing() Option {
+	return func(opts *options) {
+		opts.keepGoing = true
+	}
+}
+
+// Caches the outputs of steps that declare inputs or outputs in the given directory, skipping
+// them when nothing that went into them has changed
+func WithCacheDir(dir string) Option {
+	return func(opts *options) {
+		opts.cacheDir = dir
+	}
+}
+
+func getOptions(opts []Option) options {
+	resolved := options{limits: DefaultResourceLimits()}
+	for _, opt := range opts {
+		opt(&resolved)
+	}
+	return resolved
+}
+
+// A validated job. Steps are in the order the user listed them. A Job isn't modified by
+// scheduling, so it can be scheduled any number of times, including concurrently.
+type Job struct {
+	Steps []*Step
+}
+
+// Returns the step with the given ID, or nil if there isn't one
+func (job *Job) GetStep(stepId string) *Step {
+	for _, step := range job.Steps {
+		if step.StepId == stepId {
+			return step
+		}
+	}
+	return nil
+}
+
+// Decodes the user's YAML into raw, unvalidated steps, enforcing the input size, alias
+// expansion, step count, dependency count and ID length limits along the way
+func Parse(yamlStr string, opts ...Option) (*InputJob, error) {
+
+	limits := getOptions(opts).limits
+
+	if limits.MaxInputBytes > 0 && int64(len(yamlStr)) > limits.MaxInputBytes {
+		return nil, &LimitError{Limit: "max-input-bytes", Message: fmt.Sprintf("input is larger than the limit of %d bytes", limits.MaxInputBytes)}
+	}
+
+	// We parse to a node tree first so that aliases can be counted before decoding expands them
+	var doc yaml.Node
+	yamlMarshalErr := yaml.Unmarshal([]byte(yamlStr), &doc)
+	if yamlMarshalErr != nil {
+		return nil, &ParseError{Err: yamlMarshalErr}
+	}
+
+	if aliasErr := checkAliasExpansionLimit(&doc, limits); aliasErr != nil {
+		return nil, aliasErr
+	}
+
+	inputJob := &InputJob{}
+	if doc.Kind != 0 { // An empty document has nothing to decode
+		if decodeErr := doc.Decode(&inputJob.Steps); decodeErr != nil {
+			return nil, &ParseError{Err: decodeErr}
+		}
+	}
+
+	if limitErr := checkStepLimits(inputJob.Steps, limits); limitErr != nil {
+		return nil, limitErr
+	}
+
+	return inputJob, nil
+}
+
+// Checks every step against the job rules and links up the dependency graph. Cycles aren't
+// detected until Schedule, since finding them takes the same walk as scheduling.
+func Validate(inputJob *InputJob) (*Job, error) {
+
+	steps, stepsErr := getStepsByIdSlice(inputJob.Steps)
+	if stepsErr != nil {
+		return nil, stepsErr
+	}
+
+	if len(steps) == 0 {
+		return nil, &ValidationError{Message: "no steps were provided by user"}
+	}
+
+	return &Job{Steps: steps}, nil
+}
+
+// Returns the step IDs of the job in the order they should run. Stops with the context's
+// error (wrapped) once ctx is done, and returns a *CycleError if no ordering exists.
+func Schedule(ctx context.Context, job *Job) ([]string, error) {
+	return scheduleSteps(ctx, job.getSchedulingGraph())
+}
+
+// Copies the job's steps with fresh scheduling state, so scheduling never touches the Job itself
+func (job *Job) getSchedulingGraph() []*Step {
+
+	steps := make([]*Step, len(job.Steps))
+	stepsByIdMap := make(map[string]*Step, len(job.Steps))
+	for i, step := range job.Steps {
+		steps[i] = &Step{
+			StepName:      step.StepName,
+			StepId:        step.StepId,
+			Precedence:    step.Precedence,
+			DependencyIds: step.DependencyIds,
+			Run:           step.Run,
+			Retries:       step.Retries,
+			RetryBackoff:  step.RetryBackoff,
+			Timeout:       step.Timeout,
+			Inputs:        step.Inputs,
+			Outputs:       step.Outputs,
+			depsToClear:   make(map[string]*Step, len(step.DependencyIds)),
+		}
+		stepsByIdMap[step.StepId] = steps[i]
+	}
+
+	for _, step := range steps {
+		for _, parentStepId := range step.DependencyIds {
+			step.depsToClear[parentStepId] = stepsByIdMap[parentStepId]
+		}
+	}
+
+	return steps
+}
@@ -0,0 +1,106 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestStagesProduceSameOrderingAsProcessUserJob(t *testing.T) {
+	var tests = []struct {
+		validYamlInput string
+		expectedOutput []string
+	}{
+		{awsInfraInput, awsInfraOutput},
+		{complexValidInput, complexValidOutput},
+		{basicWithDependenciesInput, basicWithDependenciesOutput},
+	}
+
+	for i, testCase := range tests {
+		inputJob, parseErr := Parse(testCase.validYamlInput)
+		if parseErr != nil {
+			t.Errorf("test %d: received unexpected parse error: %s", i, parseErr.Error())
+			continue
+		}
+
+		job, validateErr := Validate(inputJob)
+		if validateErr != nil {
+			t.Errorf("test %d: received unexpected validation error: %s", i, validateErr.Error())
+			continue
+		}
+
+		// Scheduling twice checks that the Job isn't used up by the first run
+		for run := 0; run < 2; run++ {
+			output, scheduleErr := Schedule(context.Background(), job)
+			if scheduleErr != nil {
+				t.Errorf("test %d run %d: received unexpected schedule error: %s", i, run, scheduleErr.Error())
+			} else if !areStringSlicesEqual(output, testCase.expectedOutput) {
+				t.Errorf("test %d run %d: output did not match, got %v", i, run, output)
+			}
+		}
+	}
+}
+
+func TestErrorsHaveExpectedTypes(t *testing.T) {
+	var parseErr *ParseError
+	var limitErr *LimitError
+	var validationErr *ValidationError
+	var cycleErr *CycleError
+
+	var tests = []struct {
+		input  string
+		opts   []Option
+		target interface{}
+	}{
+		{nonYamlStringInput, nil, &parseErr},
+		{aliasBombInput, nil, &limitErr},
+		{awsInfraInput, []Option{WithLimits(ResourceLimits{MaxSteps: 2})}, &limitErr},
+		{emptyStringInput, nil, &validationErr},
+		{multipleStepsWithSameIdInput, nil, &validationErr},
+		{singleInvalidDependency, nil, &validationErr},
+		{circularDependenciesInput, nil, &cycleErr},
+	}
+
+	for i, testCase := range tests {
+		_, outputErr := ProcessUserJob(testCase.input, testCase.opts...)
+		if outputErr == nil {
+			t.Errorf("test %d: should have received error, did not", i)
+		} else if !errors.As(outputErr, testCase.target) {
+			t.Errorf("test %d: received error of unexpected type %T: %s", i, outputErr, outputErr.Error())
+		}
+	}
+}
+
+func TestCommandErrorsHaveExpectedTypes(t *testing.T) {
+	var parseErr *ParseError
+	var validationErr *ValidationError
+	var cycleErr *CycleError
+
+	var tests = []struct {
+		run    func() error
+		target interface{}
+	}{
+		{func() error { _, err := FormatUserJob(nonYamlStringInput, FormatOrderNone); return err }, &parseErr},
+		{func() error { _, err := FormatUserJob("- just a string", FormatOrderNone); return err }, &parseErr},
+		{func() error { _, err := FormatUserJob(singleMissingPrecedenceFieldInput, FormatOrderNone); return err }, &validationErr},
+		{func() error { _, err := ParseLintConfig("unknown-rule:\n  severity: error\n"); return err }, &validationErr},
+		{func() error { _, err := ExplainUserJobStep(basicWithDependenciesInput, "create user 5"); return err }, &validationErr},
+		{func() error {
+			_, err := DiffUserJobs(basicWithDependenciesInput, circularDependenciesInput)
+			return err
+		}, &cycleErr},
+		{func() error {
+			_, _, err := ResumeUserJob(basicWithDependenciesInput, []string{"create user 1"})
+			return err
+		}, &validationErr},
+	}
+
+	for i, testCase := range tests {
+		outputErr := testCase.run()
+		if outputErr == nil {
+			t.Errorf("test %d: should have received error, did not", i)
+		} else if !errors.As(outputErr, testCase.target) {
+			t.Errorf("test %d: received error of unexpected type %T: %s", i, outputErr, outputErr.Error())
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Where a step is in a Scheduler's run
+type StepState string
+
+const (
+	StepPending   StepState = "pending"
+	StepRunning   StepState = "running"
+	StepSucceeded StepState = "succeeded"
+	StepFailed    StepState = "failed"
+	StepSkipped   StepState = "skipped" // A dependency failed, so the step will never run
+)
+
+// Hands out a job's steps one at a time as their dependencies complete, for executors that run
+// steps as they go instead of working from a precomputed ordering. Ready keeps the same
+// precedence/ID order as Schedule. A Scheduler is safe for concurrent use by multiple workers.
+type Scheduler struct {
+	mutex  sync.Mutex
+	steps  []*Step
+	states map[string]StepState
+}
+
+// Builds a Scheduler for the job with every step pending. Returns a *CycleError if the job
+// can't be fully scheduled, since steps in a cycle would otherwise never become ready, or the
+// context's error if ctx is done before that's been checked.
+func NewScheduler(ctx context.Context, job *Job) (*Scheduler, error) {
+
+	if _, scheduleErr := Schedule(ctx, job); scheduleErr != nil {
+		return nil, scheduleErr
+	}
+
+	steps := job.getSchedulingGraph()
+	states := make(map[string]StepState, len(steps))
+	for _, step := range steps {
+		states[step.StepId] = StepPending
+	}
+
+	return &Scheduler{steps: steps, states: states}, nil
+}
+
+// Returns the IDs of the pending steps whose dependencies have all succeeded, in the order
+// they should be started
+func (scheduler *Scheduler) Ready() []string {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+
+	return scheduler.getReadyStepIds()
+}
+
+// Marks a ready step as running. Errors if the step doesn't exist or isn't ready.
+func (scheduler *Scheduler) Start(stepId string) error {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+
+	state, stateErr := scheduler.getState(stepId)
+	if stateErr != nil {
+		return stateErr
+	}
+	if state != StepPending {
+		return fmt.Errorf("cannot start step %s: it is %s", stepId, state)
+	}
+
+	step := scheduler.getStep(stepId)
+	if len(step.depsToClear) != 0 {
+		return fmt.Errorf("cannot start step %s: waiting on %v", stepId, getSortedStepIds(step.depsToClear))
+	}
+
+	scheduler.states[stepId] = StepRunning
+	return nil
+}
+
+// Marks a running step as succeeded, which clears it from its dependents
+func (scheduler *Scheduler) Complete(stepId string) error {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+
+	if finishErr := scheduler.finish(stepId, StepSucceeded); finishErr != nil {
+		return finishErr
+	}
+
+	for _, step := range scheduler.steps {
+		delete(step.depsToClear, stepId)
+	}
+	return nil
+}
+
+// Marks a running step as failed and every pending step downstream of it as skipped, returning
+// the skipped step IDs. Steps that don't depend on it are unaffected.
+func (scheduler *Scheduler) Fail(stepId string) ([]string, error) {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+
+	if finishErr := scheduler.finish(stepId, StepFailed); finishErr != nil {
+		return nil, finishErr
+	}
+
+	// A failed step is never cleared, so it stays in its dependents' depsToClear
+	dependentsById := make(map[string][]*Step, len(scheduler.steps))
+	for _, step := range scheduler.steps {
+		for parentStepId := range step.depsToClear {
+			dependentsById[parentStepId] = append(dependentsById[parentStepId], step)
+		}
+	}
+
+	skipped := make([]string, 0)
+	toVisit := []string{stepId}
+	for len(toVisit) > 0 {
+		blockedStepId := toVisit[0]
+		toVisit = toVisit[1:]
+
+		for _, dependent := range dependentsById[blockedStepId] {
+			if scheduler.states[dependent.StepId] != StepPending {
+				continue
+			}
+			scheduler.states[dependent.StepId] = StepSkipped
+			skipped = append(skipped, dependent.StepId)
+			toVisit = append(toVisit, dependent.StepId)
+		}
+	}
+
+	return skipped, nil
+}
+
+// Puts a running step back to pending so it can be started again, e.g. when the worker running
+// it has gone away
+func (scheduler *Scheduler) Requeue(stepId string) error {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+
+	return scheduler.finish(stepId, StepPending)
+}
+
+// Returns the state of a step, or an error if the step doesn't exist
+func (scheduler *Scheduler) State(stepId string) (StepState, error) {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+
+	return scheduler.getState(stepId)
+}
+
+// Reports whether there's nothing left to do: no step is running and none is ready
+func (scheduler *Scheduler) Done() bool {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+
+	for _, state := range scheduler.states {
+		if state == StepRunning {
+			return false
+		}
+	}
+	return len(scheduler.getReadyStepIds()) == 0
+}
+
+// Moves a running step to its next state
+func (scheduler *Scheduler) finish(stepId string, finalState StepState) error {
+
+	state, stateErr := scheduler.getState(stepId)
+	if stateErr != nil {
+		return stateErr
+	}
+	if state != StepRunning {
+		return fmt.Errorf("cannot mark step %s %s: it is %s", stepId, finalState, state)
+	}
+
+	scheduler.states[stepId] = finalState
+	return nil
+}
+
+func (scheduler *Scheduler) getState(stepId string) (StepState, error) {
+	state, stepExists := scheduler.states[stepId]
+	if !stepExists {
+		return "", fmt.Errorf("unknown step: %s", stepId)
+	}
+	return state, nil
+}
+
+func (scheduler *Scheduler) getStep(stepId string) *Step {
+	for _, step := range scheduler.steps {
+		if step.StepId == stepId {
+			return step
+		}
+	}
+	return nil
+}
+
+func (scheduler *Scheduler) getReadyStepIds() []string {
+
+	readySteps := make([]*Step, 0)
+	for _, step := range scheduler.steps {
+		if scheduler.states[step.StepId] == StepPending && len(step.depsToClear) == 0 {
+			readySteps = append(readySteps, step)
+		}
+	}
+
+	sort.Slice(readySteps, func(i, j int) bool {
+		return runsBefore(readySteps[i], readySteps[j])
+	})
+
+	readyStepIds := make([]string, len(readySteps))
+	for i, step := range readySteps {
+		readyStepIds[i] = step.StepId
+	}
+	return readyStepIds
+}
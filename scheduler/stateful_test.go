@@ -0,0 +1,206 @@
+package scheduler
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestSchedulerRunsStepsInScheduledOrder(t *testing.T) {
+	var tests = []struct {
+		validYamlInput string
+		expectedOutput []string
+	}{
+		{awsInfraInput, awsInfraOutput},
+		{complexValidInput, complexValidOutput},
+		{basicWithDependenciesInput, basicWithDependenciesOutput},
+	}
+
+	for i, testCase := range tests {
+		scheduler := getTestScheduler(t, testCase.validYamlInput)
+
+		// Always taking the first ready step one at a time should reproduce Schedule's ordering
+		output := make([]string, 0)
+		for !scheduler.Done() {
+			stepId := scheduler.Ready()[0]
+			if startErr := scheduler.Start(stepId); startErr != nil {
+				t.Fatalf("test %d: received unexpected error: %s", i, startErr.Error())
+			}
+			if completeErr := scheduler.Complete(stepId); completeErr != nil {
+				t.Fatalf("test %d: received unexpected error: %s", i, completeErr.Error())
+			}
+			output = append(output, stepId)
+		}
+
+		if !areStringSlicesEqual(output, testCase.expectedOutput) {
+			t.Errorf("test %d: output did not match, got %v", i, output)
+		}
+	}
+}
+
+func TestSchedulerRejectsInvalidTransitions(t *testing.T) {
+	scheduler := getTestScheduler(t, basicWithDependenciesInput)
+
+	if scheduler.Start("create user 1") == nil {
+		t.Errorf("should not be able to start a step with uncleared dependencies")
+	}
+	if scheduler.Start("no such step") == nil {
+		t.Errorf("should not be able to start an unknown step")
+	}
+	if scheduler.Complete("prepare database") == nil {
+		t.Errorf("should not be able to complete a step that isn't running")
+	}
+	if startErr := scheduler.Start("prepare database"); startErr != nil {
+		t.Fatalf("received unexpected error: %s", startErr.Error())
+	}
+	if scheduler.Start("prepare database") == nil {
+		t.Errorf("should not be able to start a step twice")
+	}
+	if len(scheduler.Ready()) != 0 {
+		t.Errorf("nothing should be ready while the only root is running, got %v", scheduler.Ready())
+	}
+	if scheduler.Done() {
+		t.Errorf("should not be done while a step is running")
+	}
+	if requeueErr := scheduler.Requeue("prepare database"); requeueErr != nil {
+		t.Fatalf("received unexpected error: %s", requeueErr.Error())
+	}
+	if !areStringSlicesEqual(scheduler.Ready(), []string{"prepare database"}) {
+		t.Errorf("a requeued step should be ready again, got %v", scheduler.Ready())
+	}
+	if scheduler.Requeue("prepare database") == nil {
+		t.Errorf("should not be able to requeue a step that isn't running")
+	}
+	if startErr := scheduler.Start("prepare database"); startErr != nil {
+		t.Fatalf("received unexpected error: %s", startErr.Error())
+	}
+	if _, failErr := scheduler.Fail("prepare database"); failErr != nil {
+		t.Fatalf("received unexpected error: %s", failErr.Error())
+	}
+	if scheduler.Complete("prepare database") == nil {
+		t.Errorf("should not be able to complete a failed step")
+	}
+	if scheduler.Start("create user 1") == nil {
+		t.Errorf("should not be able to start a skipped step")
+	}
+
+	// Everything depends on the failed step, so nothing else can run
+	if !scheduler.Done() {
+		t.Errorf("should be done once the only root has failed, ready: %v", scheduler.Ready())
+	}
+}
+
+func TestSchedulerSkipsDependentsOfFailedSteps(t *testing.T) {
+	var tests = []struct {
+		failStepId      string
+		expectedSkipped []string
+	}{
+		{"prepare database", []string{"create user 1", "create user 2", "create user 3", "create user 4"}},
+		{"create user 2", []string{"create user 3", "create user 4"}},
+		{"create user 4", []string{"create user 3"}},
+		{"create user 3", []string{}},
+	}
+
+	for i, testCase := range tests {
+		scheduler := getTestScheduler(t, basicWithDependenciesInput)
+
+		// Succeed everything that comes before the step we fail
+		var skipped []string
+		for !scheduler.Done() {
+			stepId := scheduler.Ready()[0]
+			if startErr := scheduler.Start(stepId); startErr != nil {
+				t.Fatalf("test %d: received unexpected error: %s", i, startErr.Error())
+			}
+			if stepId != testCase.failStepId {
+				scheduler.Complete(stepId)
+				continue
+			}
+
+			var failErr error
+			skipped, failErr = scheduler.Fail(stepId)
+			if failErr != nil {
+				t.Fatalf("test %d: received unexpected error: %s", i, failErr.Error())
+			}
+		}
+
+		sort.Strings(skipped)
+		if !areStringSlicesEqual(skipped, testCase.expectedSkipped) {
+			t.Errorf("test %d: expected skipped %v, got %v", i, testCase.expectedSkipped, skipped)
+		}
+		for _, stepId := range testCase.expectedSkipped {
+			if state, _ := scheduler.State(stepId); state != StepSkipped {
+				t.Errorf("test %d: expected %s to be skipped, got %s", i, stepId, state)
+			}
+		}
+	}
+}
+
+func TestSchedulerRejectsCycles(t *testing.T) {
+	inputJob, parseErr := Parse(circularDependenciesInput)
+	if parseErr != nil {
+		t.Fatalf("received unexpected error: %s", parseErr.Error())
+	}
+	job, validateErr := Validate(inputJob)
+	if validateErr != nil {
+		t.Fatalf("received unexpected error: %s", validateErr.Error())
+	}
+	if _, schedulerErr := NewScheduler(context.Background(), job); schedulerErr == nil {
+		t.Errorf("should have received error, did not")
+	}
+}
+
+func TestSchedulerIsSafeForConcurrentWorkers(t *testing.T) {
+	scheduler := getTestScheduler(t, complexValidInput)
+
+	var outputMutex sync.Mutex
+	output := make([]string, 0)
+
+	var waitGroup sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			for !scheduler.Done() {
+				// Another worker may start the step first, in which case we look again
+				for _, stepId := range scheduler.Ready() {
+					if scheduler.Start(stepId) != nil {
+						continue
+					}
+					outputMutex.Lock()
+					output = append(output, stepId)
+					outputMutex.Unlock()
+					if completeErr := scheduler.Complete(stepId); completeErr != nil {
+						t.Errorf("received unexpected error: %s", completeErr.Error())
+					}
+					break
+				}
+			}
+		}()
+	}
+	waitGroup.Wait()
+
+	// Every step should have run exactly once
+	expectedOutput := append([]string{}, complexValidOutput...)
+	sort.Strings(expectedOutput)
+	sort.Strings(output)
+	if !areStringSlicesEqual(output, expectedOutput) {
+		t.Errorf("output did not match, got %v", output)
+	}
+}
+
+func getTestScheduler(t *testing.T, yamlStr string) *Scheduler {
+	inputJob, parseErr := Parse(yamlStr)
+	if parseErr != nil {
+		t.Fatalf("received unexpected error: %s", parseErr.Error())
+	}
+	job, validateErr := Validate(inputJob)
+	if validateErr != nil {
+		t.Fatalf("received unexpected error: %s", validateErr.Error())
+	}
+	scheduler, schedulerErr := NewScheduler(context.Background(), job)
+	if schedulerErr != nil {
+		t.Fatalf("received unexpected error: %s", schedulerErr.Error())
+	}
+	return scheduler
+}
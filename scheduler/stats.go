@@ -0,0 +1,238 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"math/bits"
+	"sort"
+	"strings"
+)
+
+// Size and shape of a job's dependency graph, for capacity planning
+type JobStats struct {
+	Steps        int               `json:"steps"`
+	Edges        int               `json:"edges"`         // Distinct dependencies across all steps
+	Depth        int               `json:"depth"`         // Steps in the longest dependency chain
+	LongestChain []string          `json:"longest_chain"` // One chain of that length, first step first
+	WaveWidths   []int             `json:"wave_widths"`   // Steps in each wave, where a wave is every step whose longest chain of dependencies is the same length
+	MaxWaveWidth int               `json:"max_wave_width"`
+	WidestWave   int               `json:"widest_wave"` // The first wave with MaxWaveWidth steps, counting from 1
+	Roots        []string          `json:"roots"`       // Steps with no dependencies
+	Leaves       []string          `json:"leaves"`      // Steps nothing depends on
+	BlastRadius  []StepDependents  `json:"blast_radius"`
+	Precedences  []PrecedenceCount `json:"precedences"` // Highest precedence first, like the scheduler
+}
+
+// How many steps depend on a step, directly or indirectly
+type StepDependents struct {
+	StepId     string `json:"step"`
+	Dependents int    `json:"dependents"`
+}
+
+// How many steps share a precedence
+type PrecedenceCount struct {
+	Precedence int64 `json:"precedence"`
+	Steps      int   `json:"steps"`
+}
+
+func (stats *JobStats) String() string {
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("steps: %d\n", stats.Steps))
+	builder.WriteString(fmt.Sprintf("edges: %d\n", stats.Edges))
+	builder.WriteString(fmt.Sprintf("depth: %d (%s)\n", stats.Depth, strings.Join(stats.LongestChain, " -> ")))
+
+	waveWidths := make([]string, len(stats.WaveWidths))
+	for i, width := range stats.WaveWidths {
+		waveWidths[i] = fmt.Sprint(width)
+	}
+	builder.WriteString(fmt.Sprintf("max wave width: %d (wave %d, widths %s)\n", stats.MaxWaveWidth, stats.WidestWave, strings.Join(waveWidths, ", ")))
+
+	builder.WriteString(fmt.Sprintf("roots (%d): %s\n", len(stats.Roots), getStepListSummary(stats.Roots)))
+	builder.WriteString(fmt.Sprintf("leaves (%d): %s\n", len(stats.Leaves), getStepListSummary(stats.Leaves)))
+
+	if len(stats.BlastRadius) == 0 {
+		builder.WriteString("blast radius: no step has dependents\n")
+	} else {
+		builder.WriteString("blast radius:\n")
+		for _, stepDependents := range stats.BlastRadius {
+			builder.WriteString(fmt.Sprintf("  %6d  %s\n", stepDependents.Dependents, stepDependents.StepId))
+		}
+	}
+
+	builder.WriteString("precedences:\n")
+	for _, precedenceCount := range stats.Precedences {
+		builder.WriteString(fmt.Sprintf("  %6d  %d step(s)\n", precedenceCount.Precedence, precedenceCount.Steps))
+	}
+
+	return builder.String()
+}
+
+// How many roots or leaves String lists before summarizing the rest. JSON output has them all.
+const maxListedSteps = 10
+
+func getStepListSummary(stepIds []string) string {
+	if len(stepIds) <= maxListedSteps {
+		return strings.Join(stepIds, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(stepIds[:maxListedSteps], ", "), len(stepIds)-maxListedSteps)
+}
+
+// Parses and validates the user's job and measures its graph, listing the topCount steps with
+// the most dependents (all of them if topCount is 0)
+func StatsUserJob(yamlStr string, topCount int, opts ...Option) (*JobStats, error) {
+
+	ctx, cancel := NewProcessingContext(getOptions(opts).limits)
+	defer cancel()
+
+	job, jobErr := getValidatedJob(yamlStr, opts...)
+	if jobErr != nil {
+		return nil, jobErr
+	}
+
+	return GetJobStats(ctx, job, topCount)
+}
+
+// Measures the job's graph. It is scheduled first, which finds any cycle and gives an order in
+// which every step comes after its dependencies.
+func GetJobStats(ctx context.Context, job *Job, topCount int) (*JobStats, error) {
+
+	ordering, scheduleErr := Schedule(ctx, job)
+	if scheduleErr != nil {
+		return nil, scheduleErr
+	}
+
+	stepsByIdMap := getStepsByIdMap(job.Steps)
+	positionsById := make(map[string]int, len(ordering))
+	for position, stepId := range ordering {
+		positionsById[stepId] = position
+	}
+	stats := &JobStats{
+		Steps:  len(ordering),
+		Roots:  make([]string, 0),
+		Leaves: make([]string, 0),
+	}
+
+	// Dependents of each step, by 0-based position, with duplicate dependencies counted once
+	dependentsByPosition := make([][]int, len(ordering))
+	dependencySets := make([]map[string]bool, len(ordering))
+	for position, stepId := range ordering {
+		dependencySets[position] = getDependencySet(stepsByIdMap[stepId])
+		for parentStepId := range dependencySets[position] {
+			parentPosition := positionsById[parentStepId]
+			dependentsByPosition[parentPosition] = append(dependentsByPosition[parentPosition], position)
+		}
+		stats.Edges += len(dependencySets[position])
+	}
+
+	// Each step's chain length is one more than its longest dependency's, which is already known
+	// since dependencies come first in the ordering. Ties go to the lexicographically first step.
+	chainLengths := make([]int, len(ordering))
+	previousInChain := make([]string, len(ordering))
+	chainEnd := ""
+	for position, stepId := range ordering {
+		for _, parentStepId := range getSortedKeys(dependencySets[position]) {
+			if parentLength := chainLengths[positionsById[parentStepId]]; parentLength > chainLengths[position] {
+				chainLengths[position] = parentLength
+				previousInChain[position] = parentStepId
+			}
+		}
+		chainLengths[position]++
+
+		if chainLengths[position] > stats.Depth || (chainLengths[position] == stats.Depth && stepId < chainEnd) {
+			stats.Depth = chainLengths[position]
+			chainEnd = stepId
+		}
+	}
+
+	stats.LongestChain = make([]string, stats.Depth)
+	for i, stepId := stats.Depth-1, chainEnd; i >= 0; i-- {
+		stats.LongestChain[i] = stepId
+		stepId = previousInChain[positionsById[stepId]]
+	}
+
+	stats.WaveWidths = make([]int, stats.Depth)
+	for _, chainLength := range chainLengths {
+		stats.WaveWidths[chainLength-1]++
+	}
+	for wave, width := range stats.WaveWidths {
+		if width > stats.MaxWaveWidth {
+			stats.MaxWaveWidth = width
+			stats.WidestWave = wave + 1
+		}
+	}
+
+	for position, stepId := range ordering {
+		if len(dependencySets[position]) == 0 {
+			stats.Roots = append(stats.Roots, stepId)
+		}
+		if len(dependentsByPosition[position]) == 0 {
+			stats.Leaves = append(stats.Leaves, stepId)
+		}
+	}
+	sort.Strings(stats.Roots)
+	sort.Strings(stats.Leaves)
+
+	stats.BlastRadius = getBlastRadius(ordering, dependentsByPosition, topCount)
+	stats.Precedences = getPrecedenceCounts(job.Steps)
+
+	return stats, nil
+}
+
+// Counts every step's transitive dependents, most first, keeping the top topCount that have any.
+// Each step's dependents are kept as a bitset of positions and built from its direct dependents'
+// sets in reverse scheduling order, so shared subgraphs are only walked once.
+func getBlastRadius(ordering []string, dependentsByPosition [][]int, topCount int) []StepDependents {
+
+	words := (len(ordering) + 63) / 64
+	descendants := make([][]uint64, len(ordering))
+	blastRadius := make([]StepDependents, 0)
+	for position := len(ordering) - 1; position >= 0; position-- {
+		descendants[position] = make([]uint64, words)
+		for _, dependentPosition := range dependentsByPosition[position] {
+			descendants[position][dependentPosition/64] |= 1 << (dependentPosition % 64)
+			for word, dependentWord := range descendants[dependentPosition] {
+				descendants[position][word] |= dependentWord
+			}
+		}
+
+		dependentCount := 0
+		for _, word := range descendants[position] {
+			dependentCount += bits.OnesCount64(word)
+		}
+		if dependentCount > 0 {
+			blastRadius = append(blastRadius, StepDependents{StepId: ordering[position], Dependents: dependentCount})
+		}
+	}
+
+	sort.Slice(blastRadius, func(i, j int) bool {
+		if blastRadius[i].Dependents != blastRadius[j].Dependents {
+			return blastRadius[i].Dependents > blastRadius[j].Dependents
+		}
+		return blastRadius[i].StepId < blastRadius[j].StepId
+	})
+
+	if topCount > 0 && len(blastRadius) > topCount {
+		blastRadius = blastRadius[:topCount]
+	}
+
+	return blastRadius
+}
+
+func getPrecedenceCounts(steps []*Step) []PrecedenceCount {
+
+	countsByPrecedence := make(map[int64]int)
+	for _, step := range steps {
+		countsByPrecedence[step.Precedence]++
+	}
+
+	precedenceCounts := make([]PrecedenceCount, 0, len(countsByPrecedence))
+	for precedence, count := range countsByPrecedence {
+		precedenceCounts = append(precedenceCounts, PrecedenceCount{Precedence: precedence, Steps: count})
+	}
+	sort.Slice(precedenceCounts, func(i, j int) bool {
+		return precedenceCounts[i].Precedence > precedenceCounts[j].Precedence
+	})
+
+	return precedenceCounts
+}
@@ -0,0 +1,98 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCorrectlyMeasuresJobGraphs(t *testing.T) {
+	var tests = []struct {
+		yamlInput    string
+		topCount     int
+		edges        int
+		longestChain []string
+		waveWidths   []int
+		widestWave   int
+		roots        []string
+		leaves       []string
+		blastRadius  []string
+		precedences  []int64
+	}{
+		{oneStepInput, 0, 0, []string{"prepare database"}, []int{1}, 1, []string{"prepare database"}, []string{"prepare database"}, []string{}, []int64{50}},
+		{
+			basicWithDependenciesInput, 0, 4,
+			[]string{"prepare database", "create user 2", "create user 4", "create user 3"},
+			[]int{1, 2, 1, 1}, 2,
+			[]string{"prepare database"},
+			[]string{"create user 1", "create user 3"},
+			[]string{"prepare database", "create user 2", "create user 4"},
+			[]int64{100, 50, 10},
+		},
+		{
+			complexWithDependenciesInput, 2, 6,
+			[]string{"deploy lambda function", "deploy api gateway", "enable cdn distribution"},
+			[]int{4, 1, 1}, 1,
+			[]string{"create bucket", "deploy database", "deploy lambda function", "enable dns records"},
+			[]string{"enable cdn distribution"},
+			[]string{"deploy lambda function", "enable dns records"},
+			[]int64{200, 100, 50, 20},
+		},
+	}
+
+	for i, testCase := range tests {
+		stats, statsErr := StatsUserJob(testCase.yamlInput, testCase.topCount)
+		if statsErr != nil {
+			t.Errorf("test %d: expected success, got error: %s", i, statsErr.Error())
+			continue
+		}
+
+		if stats.Edges != testCase.edges || stats.Depth != len(testCase.longestChain) || stats.WidestWave != testCase.widestWave {
+			t.Errorf("test %d: unexpected counts, got:\n%s", i, stats.String())
+		}
+		if !areStringSlicesEqual(stats.LongestChain, testCase.longestChain) {
+			t.Errorf("test %d: expected longest chain %v, got %v", i, testCase.longestChain, stats.LongestChain)
+		}
+		if len(stats.WaveWidths) != len(testCase.waveWidths) {
+			t.Errorf("test %d: expected wave widths %v, got %v", i, testCase.waveWidths, stats.WaveWidths)
+		} else {
+			for wave, width := range testCase.waveWidths {
+				if stats.WaveWidths[wave] != width {
+					t.Errorf("test %d: expected wave widths %v, got %v", i, testCase.waveWidths, stats.WaveWidths)
+					break
+				}
+			}
+		}
+		if !areStringSlicesEqual(stats.Roots, testCase.roots) || !areStringSlicesEqual(stats.Leaves, testCase.leaves) {
+			t.Errorf("test %d: expected roots %v and leaves %v, got %v and %v", i, testCase.roots, testCase.leaves, stats.Roots, stats.Leaves)
+		}
+
+		blastRadiusIds := make([]string, len(stats.BlastRadius))
+		for j, stepDependents := range stats.BlastRadius {
+			blastRadiusIds[j] = stepDependents.StepId
+		}
+		if !areStringSlicesEqual(blastRadiusIds, testCase.blastRadius) {
+			t.Errorf("test %d: expected blast radius %v, got %v", i, testCase.blastRadius, blastRadiusIds)
+		}
+
+		precedenceSteps := 0
+		for j, precedenceCount := range stats.Precedences {
+			if j >= len(testCase.precedences) || precedenceCount.Precedence != testCase.precedences[j] {
+				t.Errorf("test %d: expected precedences %v, got %v", i, testCase.precedences, stats.Precedences)
+				break
+			}
+			precedenceSteps += precedenceCount.Steps
+		}
+		if precedenceSteps != stats.Steps {
+			t.Errorf("test %d: precedence counts add up to %d, expected %d", i, precedenceSteps, stats.Steps)
+		}
+	}
+}
+
+func TestStatsRejectsCycles(t *testing.T) {
+	_, statsErr := StatsUserJob(circularDependenciesInput, 0)
+
+	var cycleErr *CycleError
+	if !errors.As(statsErr, &cycleErr) {
+		t.Errorf("expected a cycle error, got %v", statsErr)
+	}
+}
@@ -0,0 +1,66 @@
+package scheduler
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Turns a raw step or dependency value into its ID: trimmed of leading and trailing whitespace
+// (which includes the trailing "\r" left behind by CRLF files) and NFC-normalized, so that the
+// precomposed and combining-mark spellings of the same text are the same ID.
+func NormalizeStepId(raw string) string {
+	return norm.NFC.String(strings.TrimSpace(raw))
+}
+
+// Returns the first rune that isn't allowed in a step ID. Control characters would break the
+// newline-delimited output (or hide in it), line and paragraph separators act like newlines,
+// and format characters such as zero-width spaces make two different IDs look identical.
+func getInvalidStepIdRune(stepId string) (rune, bool) {
+	for _, r := range stepId {
+		if r == unicode.ReplacementChar || unicode.In(r, unicode.Cc, unicode.Cf, unicode.Zl, unicode.Zp) {
+			return r, true
+		}
+	}
+	return 0, false
+}
+
+// Common homoglyphs of Latin letters, mapped to the Latin letter they're mistaken for
+var confusableRunes = map[rune]rune{
+	// Cyrillic
+	'а': 'a', 'в': 'b', 'е': 'e', 'ѕ': 's', 'і': 'i', 'ј': 'j', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o',
+	'р': 'p', 'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'ԁ': 'd', 'ԛ': 'q', 'ԝ': 'w', 'һ': 'h',
+	'А': 'A', 'В': 'B', 'Е': 'E', 'Ѕ': 'S', 'І': 'I', 'Ј': 'J', 'К': 'K', 'М': 'M', 'Н': 'H',
+	'О': 'O', 'Р': 'P', 'С': 'C', 'Т': 'T', 'Х': 'X', 'Ү': 'Y',
+	// Greek
+	'α': 'a', 'ο': 'o', 'ν': 'v', 'ρ': 'p', 'τ': 't', 'υ': 'u', 'ι': 'i', 'κ': 'k',
+	'Α': 'A', 'Β': 'B', 'Ε': 'E', 'Ζ': 'Z', 'Η': 'H', 'Ι': 'I', 'Κ': 'K', 'Μ': 'M', 'Ν': 'N',
+	'Ο': 'O', 'Ρ': 'P', 'Τ': 'T', 'Υ': 'Y', 'Χ': 'X',
+}
+
+// Reduces a step ID to a skeleton that's the same for IDs that would look the same on screen:
+// compatibility forms (fullwidth letters, ligatures, non-breaking spaces) are folded by NFKC,
+// common Cyrillic and Greek homoglyphs become Latin, and runs of spaces collapse to one.
+func getConfusableSkeleton(stepId string) string {
+
+	var builder strings.Builder
+	lastWasSpace := false
+	for _, r := range norm.NFKC.String(stepId) {
+		if unicode.IsSpace(r) {
+			if !lastWasSpace {
+				builder.WriteRune(' ')
+			}
+			lastWasSpace = true
+			continue
+		}
+		lastWasSpace = false
+
+		if latin, isConfusable := confusableRunes[r]; isConfusable {
+			r = latin
+		}
+		builder.WriteRune(r)
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,138 @@
+package scheduler
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Levenshtein distance between two strings, counted in runes
+func getEditDistance(a string, b string) int {
+
+	aRunes, bRunes := []rune(a), []rune(b)
+
+	previous := make([]int, len(bRunes)+1)
+	current := make([]int, len(bRunes)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(aRunes); i++ {
+		current[0] = i
+		for j := 1; j <= len(bRunes); j++ {
+			cost := 1
+			if aRunes[i-1] == bRunes[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(minInt(previous[j]+1, current[j-1]+1), previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(bRunes)]
+}
+
+// The largest edit distance we'll treat as a typo of an ID this long. Short IDs get less
+// slack so that e.g. "db" isn't "corrected" to "ui".
+func getMaxTypoDistance(stepId string) int {
+	return minInt(2, len([]rune(stepId))/4)
+}
+
+// Returns the one step ID that unknownId is almost certainly a typo of: either the only
+// case-insensitive match, or the only ID at the smallest edit distance within typo range.
+// Returns false if there's no such ID, or if it's ambiguous. IDs that differ only in their
+// digits, like "deploy v2" and "deploy v1", are numbered steps rather than typos, so a closest
+// match like that is never returned either.
+func getNearMissStepId(unknownId string, stepIds []string) (string, bool) {
+
+	caseMatches := make([]string, 0)
+	for _, stepId := range stepIds {
+		if strings.EqualFold(stepId, unknownId) {
+			caseMatches = append(caseMatches, stepId)
+		}
+	}
+	if len(caseMatches) == 1 {
+		return caseMatches[0], true
+	}
+	if len(caseMatches) > 1 {
+		return "", false
+	}
+
+	maxDistance := getMaxTypoDistance(unknownId)
+	bestDistance := maxDistance + 1
+	bestIds := make([]string, 0)
+	for _, stepId := range stepIds {
+		distance := getEditDistance(unknownId, stepId)
+		if distance < bestDistance {
+			bestDistance = distance
+			bestIds = []string{stepId}
+		} else if distance == bestDistance {
+			bestIds = append(bestIds, stepId)
+		}
+	}
+
+	if len(bestIds) != 1 || bestDistance > maxDistance || isDigitOnlyDifference(unknownId, bestIds[0]) {
+		return "", false
+	}
+
+	return bestIds[0], true
+}
+
+// Whether two different IDs would be the same with their digits removed
+func isDigitOnlyDifference(a string, b string) bool {
+
+	removeDigits := func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return -1
+		}
+		return r
+	}
+
+	return a != b && strings.Map(removeDigits, a) == strings.Map(removeDigits, b)
+}
+
+// Returns up to maxSuggestions step IDs that unknownId might have been meant as, closest first.
+// Case-insensitive matches always come first; after that, anything within a third of the ID's
+// length in edit distance.
+func getSuggestedStepIds(unknownId string, stepIds []string, maxSuggestions int) []string {
+
+	type candidate struct {
+		stepId   string
+		distance int
+	}
+
+	maxDistance := len([]rune(unknownId)) / 3
+	if maxDistance < 1 {
+		maxDistance = 1
+	}
+
+	candidates := make([]candidate, 0)
+	for _, stepId := range stepIds {
+		if strings.EqualFold(stepId, unknownId) {
+			candidates = append(candidates, candidate{stepId, -1})
+		} else if distance := getEditDistance(unknownId, stepId); distance <= maxDistance {
+			candidates = append(candidates, candidate{stepId, distance})
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].stepId < candidates[j].stepId
+	})
+
+	suggestions := make([]string, 0, maxSuggestions)
+	for i := 0; i < len(candidates) && i < maxSuggestions; i++ {
+		suggestions = append(suggestions, candidates[i].stepId)
+	}
+
+	return suggestions
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,74 @@
+package scheduler
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNearMissStepIds(t *testing.T) {
+	stepIds := []string{"prepare database", "create user 1", "create user 2", "deploy v1", "Deploy V1", "build api", "build app", "db", "ui"}
+
+	var tests = []struct {
+		unknownId  string
+		correctId  string
+		isNearMiss bool
+	}{
+		{"prepare databse", "prepare database", true},
+		{"Prepare Database", "prepare database", true},
+		{"create usr 1", "create user 1", true},
+		{"create user", "", false},    // Equally close to create user 1 and 2
+		{"build apx", "", false},      // Equally close to build api and build app
+		{"DEPLOY V1", "", false},      // Matches both deploy v1 and Deploy V1 ignoring case
+		{"deploy v2", "", false},      // Differs only in a digit, which makes it a different step
+		{"create user 12", "", false}, // The same, even though create user 1 is the only closest
+		{"create user 3", "", false},
+		{"dc", "", false},
+		{"deploy everything", "", false},
+	}
+
+	for i, testCase := range tests {
+		correctedId, isNearMiss := getNearMissStepId(testCase.unknownId, stepIds)
+		if correctedId != testCase.correctId || isNearMiss != testCase.isNearMiss {
+			t.Errorf("test %d: expected %q %v, got %q %v", i, testCase.correctId, testCase.isNearMiss, correctedId, isNearMiss)
+		}
+	}
+}
+
+func TestSuggestedStepIds(t *testing.T) {
+	stepIds := []string{"prepare database", "Prepare Database", "prepare databases", "create user 1", "db"}
+
+	var tests = []struct {
+		unknownId   string
+		suggestions []string
+	}{
+		{"PREPARE DATABASE", []string{"Prepare Database", "prepare database"}},
+		{"prepare databse", []string{"prepare database", "prepare databases", "Prepare Database"}},
+		{"create user", []string{"create user 1"}},
+		{"dv", []string{"db"}},
+		{"deploy everything", []string{}},
+	}
+
+	for i, testCase := range tests {
+		suggestions := getSuggestedStepIds(testCase.unknownId, stepIds, 3)
+		if !areStringSlicesEqual(suggestions, testCase.suggestions) {
+			t.Errorf("test %d: did not get equal suggestions, got: %v", i, suggestions)
+		}
+	}
+}
+
+func TestInvalidDependencyErrorSuggestsStepIds(t *testing.T) {
+	_, outputErr := ProcessUserJob(multipleInvalidDependency)
+	if outputErr == nil {
+		t.Fatalf("should have received error, did not")
+	}
+
+	for _, expected := range []string{
+		"invalid dependency specified: enable dns recordss",
+		"referenced by: deploy api gateway",
+		"did you mean: enable dns records?",
+	} {
+		if !strings.Contains(outputErr.Error(), expected) {
+			t.Errorf("expected error to contain %q, got: %s", expected, outputErr.Error())
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package scheduler
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Represents the raw unvalidated data coming in from the user input
+type InputJobStep struct {
+	StepName             string   `yaml:"step"`
+	Dependencies         []string `yaml:"dependencies"`
+	PrecedenceRaw        string   `yaml:"precedence"`
+	Run                  *StepRun `yaml:"run"`
+	RetriesRaw           string   `yaml:"retries"`
+	RetryBackoffRaw      string   `yaml:"retry_backoff"`
+	TimeoutRaw           string   `yaml:"timeout"`
+	Inputs               []string `yaml:"inputs"`
+	Outputs              []string `yaml:"outputs"`
+	precedenceCalculated int64    //Our calculated value after we convert from user input
+	stepIdNormalized     string   //StepName trimmed and NFC-normalized, set by ValidateInputStep
+	retries              int
+	retryBackoff         time.Duration
+	timeout              time.Duration
+}
+
+// The optional command a step runs when the job is executed. Steps without one are
+// treated as markers that succeed straight away.
+type StepRun struct {
+	Command string            `yaml:"command" json:"command"`
+	Args    []string          `yaml:"args" json:"args,omitempty"`
+	Env     map[string]string `yaml:"env" json:"env,omitempty"` // Added to the environment the executor was started with
+}
+
+type InputJob struct {
+	Steps []*InputJobStep
+}
+
+// Returns whether the user inputted step was valid. If false, error will
+// contain the details about the failure and should be handled above.
+func (inputStep *InputJobStep) ValidateInputStep() error {
+
+	stepName := inputStep.StepName
+	if stepName == "" {
+		return fmt.Errorf("no step name provided, invalid")
+	}
+
+	stepId := NormalizeStepId(stepName)
+	if stepId == "" {
+		return fmt.Errorf("step ID would be empty, invalid name")
+	}
+
+	if strings.Contains(stepId, "\n") {
+		return fmt.Errorf("newline detected in the StepId")
+	}
+
+	if invalidRune, hasInvalidRune := getInvalidStepIdRune(stepId); hasInvalidRune {
+		return fmt.Errorf("invalid character %U detected in the StepId %q", invalidRune, stepId)
+	}
+
+	inputStep.stepIdNormalized = stepId
+
+	// Check precedence rules. Field must exist (so empty value is invalid)
+	// Must be positive non-zero integer
+	// Our input is a string field so the YAML unmarshal didn't reformat our numbers
+	// If the string exists and is a non-zero integer, it validates. In GetStep()
+	// we'll do the work of converting it to an int64
+	precedenceStr := strings.TrimSpace(inputStep.PrecedenceRaw)
+	if precedenceStr == "" {
+		return fmt.Errorf("no precedence was provided")
+	}
+
+	precedenceCalc, calcErr := strconv.ParseInt(precedenceStr, 10, 64)
+	if calcErr != nil || precedenceCalc <= 0 {
+		return fmt.Errorf("invalid int provided")
+	}
+
+	inputStep.precedenceCalculated = precedenceCalc
+
+	depIdsSanitized := make([]string, len(inputStep.Dependencies))
+	for i, depIdStr := range inputStep.Dependencies {
+		sanitized := NormalizeStepId(depIdStr)
+		if sanitized == "" {
+			return fmt.Errorf("empty dependency id passed")
+		}
+		if invalidRune, hasInvalidRune := getInvalidStepIdRune(sanitized); hasInvalidRune {
+			return fmt.Errorf("invalid character %U detected in dependency id %q", invalidRune, sanitized)
+		}
+		depIdsSanitized[i] = sanitized
+	}
+
+	inputStep.Dependencies = depIdsSanitized
+
+	// Retries and timeouts only matter when the step has something to run, but are checked regardless
+	if retriesStr := strings.TrimSpace(inputStep.RetriesRaw); retriesStr != "" {
+		retries, retriesErr := strconv.Atoi(retriesStr)
+		if retriesErr != nil || retries < 0 {
+			return fmt.Errorf("invalid retries %q, must be a non-negative integer", retriesStr)
+		}
+		inputStep.retries = retries
+	}
+
+	if backoffStr := strings.TrimSpace(inputStep.RetryBackoffRaw); backoffStr != "" {
+		backoff, backoffErr := time.ParseDuration(backoffStr)
+		if backoffErr != nil || backoff < 0 {
+			return fmt.Errorf("invalid retry_backoff %q, must be a duration such as 5s", backoffStr)
+		}
+		inputStep.retryBackoff = backoff
+	}
+
+	if timeoutStr := strings.TrimSpace(inputStep.TimeoutRaw); timeoutStr != "" {
+		timeout, timeoutErr := time.ParseDuration(timeoutStr)
+		if timeoutErr != nil || timeout <= 0 {
+			return fmt.Errorf("invalid timeout %q, must be a positive duration such as 10m", timeoutStr)
+		}
+		inputStep.timeout = timeout
+	}
+
+	// Globs are checked here so a typo is reported before anything runs
+	for _, pattern := range inputStep.Inputs {
+		if _, matchErr := filepath.Match(pattern, ""); matchErr != nil || strings.TrimSpace(pattern) == "" {
+			return fmt.Errorf("invalid input pattern %q", pattern)
+		}
+	}
+	for _, pattern := range inputStep.Outputs {
+		if _, matchErr := filepath.Match(pattern, ""); matchErr != nil || strings.TrimSpace(pattern) == "" {
+			return fmt.Errorf("invalid output pattern %q", pattern)
+		}
+		if !isLocalPath(pattern) {
+			return fmt.Errorf("output %q must be a relative path inside the working directory", pattern)
+		}
+	}
+
+	if inputStep.Run != nil {
+		if strings.TrimSpace(inputStep.Run.Command) == "" {
+			return fmt.Errorf("run was provided without a command")
+		}
+		for envKey := range inputStep.Run.Env {
+			if envKey == "" || strings.ContainsAny(envKey, "=\x00") {
+				return fmt.Errorf("invalid environment variable name %q", envKey)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Takes a user input step and returns a fully formed Step
+func (inputStep *InputJobStep) GetStep() *Step {
+
+	js := &Step{
+		StepName:      inputStep.StepName,
+		StepId:        inputStep.stepIdNormalized,
+		Precedence:    inputStep.precedenceCalculated,
+		DependencyIds: inputStep.Dependencies,
+		Run:           inputStep.Run,
+		Retries:       inputStep.retries,
+		RetryBackoff:  inputStep.retryBackoff,
+		Timeout:       inputStep.timeout,
+		Inputs:        inputStep.Inputs,
+		Outputs:       inputStep.Outputs,
+		depsToClear:   make(map[string]*Step),
+		allDepsClear:  false,
+	}
+
+	return js
+}
+
+// Represents a validated job step. The unexported fields track dependency
+// graph state while a schedule is being worked out.
+type Step struct {
+	StepName      string           // Represents the original untrimmed Step Name
+	StepId        string           // StepName trimmed of leading and trailing whitespace, NFC-normalized
+	Precedence    int64            // Sorted desc (e.g. Precedence 100 before Precedence 50)
+	DependencyIds []string         // Copies from the Input Dependencies array (represents parentss)
+	Run           *StepRun         // What to execute for this step, nil if nothing
+	Retries       int              // How many more times to run a failed command before the step fails
+	RetryBackoff  time.Duration    // Wait before the first retry, doubling for each one after
+	Timeout       time.Duration    // Longest a single attempt may run, 0 for no limit
+	Inputs        []string         // Globs of the files the step reads, hashed into its cache key
+	Outputs       []string         // Globs of the files the step writes, restored on a cache hit
+	depsToClear   map[string]*Step // Parent Depdendencies
+	allDepsClear  bool             // Whether all dependencies are clear for this item
+}
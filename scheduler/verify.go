@@ -0,0 +1,161 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The rules an ordering can break, as reported in an OrderingViolation
+const (
+	RuleUnknownStep     = "unknown-step"     // The ordering names a step that isn't in the job
+	RuleDuplicateStep   = "duplicate-step"   // A step appears more than once
+	RuleMissingStep     = "missing-step"     // A step in the job never appears
+	RuleDependencyOrder = "dependency-order" // A step appears before one of its dependencies
+	RulePrecedence      = "precedence"       // A higher-precedence step was ready and should have gone first
+	RuleLexicographic   = "lexicographic"    // An equal-precedence step with a smaller ID was ready and should have gone first
+)
+
+// A single way in which an ordering fails to match the job. Position is the 1-based index
+// into the ordering, or 0 for a step that's missing from the ordering entirely.
+type OrderingViolation struct {
+	Position int
+	StepId   string
+	Rule     string
+	Message  string
+}
+
+func (violation OrderingViolation) String() string {
+	position := "end"
+	if violation.Position > 0 {
+		position = fmt.Sprintf("%d", violation.Position)
+	}
+	return fmt.Sprintf("position %s: [%s] %s", position, violation.Rule, violation.Message)
+}
+
+// Checks a (possibly hand-edited) ordering against the job, returning every violation found.
+// An empty slice means the ordering is exactly one the scheduler would accept. An error is only
+// returned if the job itself can't be parsed or validated, or the processing timeout runs out.
+func VerifyUserJobOrdering(yamlStr string, ordering []string, opts ...Option) ([]OrderingViolation, error) {
+
+	ctx, cancel := NewProcessingContext(getOptions(opts).limits)
+	defer cancel()
+
+	job, jobErr := getValidatedJob(yamlStr, opts...)
+	if jobErr != nil {
+		return make([]OrderingViolation, 0), jobErr
+	}
+
+	return verifyOrdering(ctx, job.Steps, ordering)
+}
+
+// Replays the ordering one position at a time. At each position we work out which steps were
+// ready to run, and compare the step that was actually chosen against the one the scheduler
+// would have picked. Steps are considered run once they appear, even if they broke a rule,
+// so that a single mistake doesn't cascade into a violation at every later position.
+func verifyOrdering(ctx context.Context, stepsByIdSlice []*Step, ordering []string) ([]OrderingViolation, error) {
+
+	violations := make([]OrderingViolation, 0)
+
+	stepsByIdMap := getStepsByIdMap(stepsByIdSlice)
+
+	hasRun := make(map[string]bool, len(stepsByIdSlice))
+	for i, stepId := range ordering {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return make([]OrderingViolation, 0), fmt.Errorf("verification stopped: %w", ctxErr)
+		}
+		position := i + 1
+
+		step, stepOk := stepsByIdMap[stepId]
+		if !stepOk {
+			violations = append(violations, OrderingViolation{
+				Position: position,
+				StepId:   stepId,
+				Rule:     RuleUnknownStep,
+				Message:  fmt.Sprintf("step %s is not in the job", stepId),
+			})
+			continue
+		}
+
+		if hasRun[stepId] {
+			violations = append(violations, OrderingViolation{
+				Position: position,
+				StepId:   stepId,
+				Rule:     RuleDuplicateStep,
+				Message:  fmt.Sprintf("step %s was already used earlier in the ordering", stepId),
+			})
+			continue
+		}
+
+		missingDeps := getUnrunDependencies(step, hasRun)
+		if len(missingDeps) > 0 {
+			violations = append(violations, OrderingViolation{
+				Position: position,
+				StepId:   stepId,
+				Rule:     RuleDependencyOrder,
+				Message:  fmt.Sprintf("step %s runs before its dependencies: %s", stepId, strings.Join(missingDeps, ", ")),
+			})
+		} else if expected := getBestReadyStep(stepsByIdSlice, hasRun); expected != nil && expected != step {
+			rule := RuleLexicographic
+			if expected.Precedence != step.Precedence {
+				rule = RulePrecedence
+			}
+			violations = append(violations, OrderingViolation{
+				Position: position,
+				StepId:   stepId,
+				Rule:     rule,
+				Message: fmt.Sprintf("step %s (precedence %d) was chosen, but %s (precedence %d) was ready and should run first",
+					stepId, step.Precedence, expected.StepId, expected.Precedence),
+			})
+		}
+
+		hasRun[stepId] = true
+	}
+
+	for _, step := range stepsByIdSlice {
+		if !hasRun[step.StepId] {
+			violations = append(violations, OrderingViolation{
+				Position: 0,
+				StepId:   step.StepId,
+				Rule:     RuleMissingStep,
+				Message:  fmt.Sprintf("step %s never appears in the ordering", step.StepId),
+			})
+		}
+	}
+
+	return violations, nil
+}
+
+// Returns the sorted IDs of any dependencies of step that haven't run yet
+func getUnrunDependencies(step *Step, hasRun map[string]bool) []string {
+
+	unrun := make([]string, 0)
+	seen := make(map[string]bool, len(step.DependencyIds))
+	for _, parentStepId := range step.DependencyIds {
+		if !hasRun[parentStepId] && !seen[parentStepId] {
+			unrun = append(unrun, parentStepId)
+		}
+		seen[parentStepId] = true
+	}
+	sort.Strings(unrun)
+
+	return unrun
+}
+
+// Returns the step the scheduler would pick next given the steps that have already run,
+// or nil if no step is ready. Unlike getNextAvailableStep, this leaves the graph untouched.
+func getBestReadyStep(stepsByIdSlice []*Step, hasRun map[string]bool) *Step {
+
+	var best *Step
+	for _, step := range stepsByIdSlice {
+		if hasRun[step.StepId] || len(getUnrunDependencies(step, hasRun)) > 0 {
+			continue
+		}
+		if best == nil || runsBefore(step, best) {
+			best = step
+		}
+	}
+
+	return best
+}
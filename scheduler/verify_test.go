@@ -0,0 +1,100 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCorrectlyVerifiesOrderings(t *testing.T) {
+	var tests = []struct {
+		yamlInput         string
+		ordering          []string
+		expectedRules     []string
+		expectedPositions []int
+	}{
+		{basicWithDependenciesInput, basicWithDependenciesOutput, []string{}, []int{}},
+		{complexValidInput, complexValidOutput, []string{}, []int{}},
+		{
+			basicWithDependenciesInput,
+			[]string{"prepare database", "create user 2", "create user 1", "create user 4", "create user 3"},
+			[]string{RulePrecedence},
+			[]int{2},
+		},
+		{
+			multipleNoDependenciesInput,
+			[]string{"enable dns records", "enable cdn distribution", "deploy api gateway", "deploy database", "deploy lambda function", "create bucket"},
+			[]string{RuleLexicographic},
+			[]int{2},
+		},
+		{
+			basicWithDependenciesInput,
+			[]string{"create user 1", "prepare database", "create user 2", "create user 4", "create user 3"},
+			[]string{RuleDependencyOrder},
+			[]int{1},
+		},
+		{
+			basicWithDependenciesInput,
+			[]string{"prepare database", "create user 1", "create user 2", "create user 4", "create user 5"},
+			[]string{RuleUnknownStep, RuleMissingStep},
+			[]int{5, 0},
+		},
+		{
+			basicWithDependenciesInput,
+			[]string{"prepare database", "create user 1", "create user 2", "create user 4", "create user 3", "prepare database"},
+			[]string{RuleDuplicateStep},
+			[]int{6},
+		},
+	}
+
+	for i, testCase := range tests {
+		violations, verifyErr := VerifyUserJobOrdering(testCase.yamlInput, testCase.ordering)
+		if verifyErr != nil {
+			t.Errorf("test %d: expected success, got error: %s", i, verifyErr.Error())
+		}
+
+		if len(violations) != len(testCase.expectedRules) {
+			t.Errorf("test %d: expected %d violations, got: %v", i, len(testCase.expectedRules), violations)
+			continue
+		}
+
+		for j, violation := range violations {
+			if violation.Rule != testCase.expectedRules[j] || violation.Position != testCase.expectedPositions[j] {
+				t.Errorf("test %d: unexpected violation: %s", i, violation.String())
+			}
+		}
+	}
+}
+
+func TestVerifyRejectsInvalidJobs(t *testing.T) {
+	_, verifyErr := VerifyUserJobOrdering(circularDependenciesInput, []string{})
+	if verifyErr != nil {
+		t.Errorf("cycles are reported as violations, got error: %s", verifyErr.Error())
+	}
+
+	_, verifyErr = VerifyUserJobOrdering(nonYamlStringInput, []string{})
+	if verifyErr == nil {
+		t.Errorf("should have received error, did not")
+	}
+}
+
+// Both replays walk the whole job through getBestReadyStep, so they have to give up once the
+// processing timeout runs out
+func TestReplaysStopWhenCancelled(t *testing.T) {
+	job, jobErr := getValidatedJob(complexWithDependenciesInput)
+	if jobErr != nil {
+		t.Fatalf("received unexpected error: %s", jobErr.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, verifyErr := verifyOrdering(ctx, job.Steps, complexWithDependenciesOutput)
+	_, explainErr := explainStep(ctx, job.Steps, "enable cdn distribution")
+
+	for i, replayErr := range []error{verifyErr, explainErr} {
+		if !errors.Is(replayErr, context.Canceled) {
+			t.Errorf("test %d: expected the replay to be cancelled, got %v", i, replayErr)
+		}
+	}
+}
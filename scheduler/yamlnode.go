@@ -0,0 +1,60 @@
+package scheduler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Parses the user's YAML into a node tree, returning the document and each step's mapping node
+func getJobDocument(yamlStr string) (*yaml.Node, []*yaml.Node, error) {
+
+	var doc yaml.Node
+	if yamlMarshalErr := yaml.Unmarshal([]byte(yamlStr), &doc); yamlMarshalErr != nil {
+		return nil, nil, &ParseError{Err: yamlMarshalErr}
+	}
+
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) != 1 || doc.Content[0].Kind != yaml.SequenceNode {
+		return nil, nil, &ParseError{Err: errors.New("job must be a list of steps")}
+	}
+
+	stepNodes := doc.Content[0].Content
+	for i, stepNode := range stepNodes {
+		if stepNode.Kind != yaml.MappingNode {
+			return nil, nil, &ParseError{Err: fmt.Errorf("step %d must be a mapping", i+1)}
+		}
+	}
+
+	return &doc, stepNodes, nil
+}
+
+// Returns the value node for key in a mapping node, or nil if the key isn't there
+func getMappingValue(mappingNode *yaml.Node, key string) *yaml.Node {
+
+	// Mapping content alternates key, value, key, value...
+	for i := 0; i+1 < len(mappingNode.Content); i += 2 {
+		if mappingNode.Content[i].Value == key {
+			return mappingNode.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// Writes a node tree back out as YAML, with the two-space indent used throughout our examples
+func encodeJobDocument(doc *yaml.Node) (string, error) {
+
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if encodeErr := encoder.Encode(doc); encodeErr != nil {
+		return "", fmt.Errorf("could not encode yaml: %s", encodeErr)
+	}
+	if closeErr := encoder.Close(); closeErr != nil {
+		return "", fmt.Errorf("could not encode yaml: %s", closeErr)
+	}
+
+	return buffer.String(), nil
+}
@@ -0,0 +1,266 @@
+// Package server exposes the scheduler over HTTP, so callers can submit a job and get its
+// ordering back without running the CLI. Jobs may be posted as YAML or JSON.
+//
+//	POST /schedule  returns {"ordering": [...]}
+//	POST /validate  returns {"valid": true, "steps": n}
+//	GET  /healthz   returns {"status": "ok"}
+//
+// Failures return a non-2xx status and {"error": {"class", "message", "limit", "steps"}}.
+// Nothing is sent if the client disconnects before its job has been scheduled.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/AdamBuchen/kurtosis-take-home/scheduler"
+)
+
+// Error classes, so callers can branch without parsing messages
+const (
+	ErrorClassRequest    = "request"    // The request itself was wrong, e.g. the method or content type
+	ErrorClassParse      = "parse"      // The body isn't YAML or JSON, or isn't a list of steps
+	ErrorClassLimit      = "limit"      // The job exceeds one of the resource limits
+	ErrorClassValidation = "validation" // The steps break one of the job rules
+	ErrorClassCycle      = "cycle"      // The dependencies are circular
+	ErrorClassTimeout    = "timeout"    // Scheduling took longer than the timeout
+	ErrorClassInternal   = "internal"
+)
+
+// The body of every failed response
+type ErrorResponse struct {
+	Error ErrorDetail `json:"error"`
+}
+
+type ErrorDetail struct {
+	Class   string   `json:"class"`
+	Message string   `json:"message"`
+	Limit   string   `json:"limit,omitempty"` // The resource limit that was exceeded, for limit errors
+	Steps   []string `json:"steps,omitempty"` // The steps the problem was found on, where known
+}
+
+type ScheduleResponse struct {
+	Ordering []string `json:"ordering"`
+}
+
+type ValidateResponse struct {
+	Valid bool `json:"valid"`
+	Steps int  `json:"steps"`
+}
+
+type HealthResponse struct {
+	Status string `json:"status"`
+}
+
+// Returns a handler serving the scheduling endpoints. Every job is held to the given limits,
+// including the request body size and the scheduling timeout.
+func NewHandler(limits scheduler.ResourceLimits) http.Handler {
+
+	jobServer := &jobServer{limits: limits}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/schedule", jobServer.handleSchedule)
+	mux.HandleFunc("/validate", jobServer.handleValidate)
+	mux.HandleFunc("/healthz", handleHealth)
+
+	return mux
+}
+
+type jobServer struct {
+	limits scheduler.ResourceLimits
+}
+
+func (jobServer *jobServer) handleSchedule(writer http.ResponseWriter, request *http.Request) {
+
+	ordering, processErr := jobServer.processJob(writer, request)
+	if processErr != nil {
+		writeError(writer, processErr)
+		return
+	}
+
+	writeJson(writer, http.StatusOK, ScheduleResponse{Ordering: ordering})
+}
+
+func (jobServer *jobServer) handleValidate(writer http.ResponseWriter, request *http.Request) {
+
+	// Scheduling is the only way to find cycles, so validating takes a full run
+	ordering, processErr := jobServer.processJob(writer, request)
+	if processErr != nil {
+		writeError(writer, processErr)
+		return
+	}
+
+	writeJson(writer, http.StatusOK, ValidateResponse{Valid: true, Steps: len(ordering)})
+}
+
+func handleHealth(writer http.ResponseWriter, request *http.Request) {
+
+	if request.Method != http.MethodGet && request.Method != http.MethodHead {
+		writeError(writer, &requestError{status: http.StatusMethodNotAllowed, message: "method not allowed, use GET", allow: "GET, HEAD"})
+		return
+	}
+
+	writeJson(writer, http.StatusOK, HealthResponse{Status: "ok"})
+}
+
+// Reads the job from the request body and schedules it. Scheduling stops if the client goes away.
+func (jobServer *jobServer) processJob(writer http.ResponseWriter, request *http.Request) ([]string, error) {
+
+	if request.Method != http.MethodPost {
+		return nil, &requestError{status: http.StatusMethodNotAllowed, message: "method not allowed, use POST", allow: "POST"}
+	}
+
+	isJson, contentTypeErr := isJsonContentType(request.Header.Get("Content-Type"))
+	if contentTypeErr != nil {
+		return nil, contentTypeErr
+	}
+
+	body, readErr := jobServer.readBody(writer, request)
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	// JSON is valid YAML, so the same parser handles both, but JSON bodies are held to JSON syntax
+	if isJson {
+		var decoded interface{}
+		if jsonErr := json.Unmarshal(body, &decoded); jsonErr != nil {
+			return nil, &jsonSyntaxError{err: jsonErr}
+		}
+	}
+
+	ctx := request.Context()
+	if jobServer.limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, jobServer.limits.Timeout)
+		defer cancel()
+	}
+
+	return scheduler.ProcessUserJobWithContext(ctx, string(body), scheduler.WithLimits(jobServer.limits))
+}
+
+// Reads the whole body, refusing anything over the input size limit. A body that can't be read,
+// usually because the client went away mid-upload, is the client's problem rather than ours.
+func (jobServer *jobServer) readBody(writer http.ResponseWriter, request *http.Request) ([]byte, error) {
+
+	reader := request.Body
+	if maxBytes := jobServer.limits.MaxInputBytes; maxBytes > 0 {
+		reader = http.MaxBytesReader(writer, request.Body, maxBytes)
+	}
+
+	body, readErr := io.ReadAll(reader)
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(readErr, &maxBytesErr):
+		return nil, &scheduler.LimitError{Limit: "max-input-bytes", Message: fmt.Sprintf("request body is larger than the limit of %d bytes", maxBytesErr.Limit)}
+	case readErr != nil:
+		return nil, &requestError{status: http.StatusBadRequest, message: "could not read request body: " + readErr.Error()}
+	}
+
+	return body, nil
+}
+
+// Accepts JSON, YAML and plain text bodies. A missing content type is treated as YAML.
+func isJsonContentType(contentType string) (bool, error) {
+
+	if contentType == "" {
+		return false, nil
+	}
+
+	mediaType, _, parseErr := mime.ParseMediaType(contentType)
+	if parseErr != nil {
+		return false, &requestError{status: http.StatusUnsupportedMediaType, message: "invalid content type: " + contentType}
+	}
+
+	switch mediaType {
+	case "application/json":
+		return true, nil
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml", "text/plain", "application/octet-stream":
+		return false, nil
+	}
+
+	return false, &requestError{status: http.StatusUnsupportedMediaType, message: "unsupported content type " + mediaType + ", use application/json or application/yaml"}
+}
+
+// A problem with the request rather than the job in it
+type requestError struct {
+	status  int
+	message string
+	allow   string // The Allow header to send with a 405
+}
+
+func (requestErr *requestError) Error() string {
+	return requestErr.message
+}
+
+// A JSON body that doesn't parse. The YAML parser would accept some of these, or report them
+// in YAML terms, so they're caught first.
+type jsonSyntaxError struct {
+	err error
+}
+
+func (syntaxErr *jsonSyntaxError) Error() string {
+	return "invalid json: " + syntaxErr.err.Error()
+}
+
+// Maps an error onto its HTTP status and response body
+func getErrorResponse(err error) (int, ErrorResponse) {
+
+	var requestErr *requestError
+	var syntaxErr *jsonSyntaxError
+	var parseErr *scheduler.ParseError
+	var limitErr *scheduler.LimitError
+	var validationErr *scheduler.ValidationError
+	var cycleErr *scheduler.CycleError
+
+	detail := ErrorDetail{Message: err.Error()}
+	status := http.StatusInternalServerError
+
+	switch {
+	case errors.As(err, &requestErr):
+		detail.Class, status = ErrorClassRequest, requestErr.status
+	case errors.As(err, &syntaxErr), errors.As(err, &parseErr):
+		detail.Class, status = ErrorClassParse, http.StatusBadRequest
+	case errors.As(err, &limitErr):
+		detail.Class, detail.Limit, status = ErrorClassLimit, limitErr.Limit, http.StatusUnprocessableEntity
+		if limitErr.Limit == "max-input-bytes" {
+			status = http.StatusRequestEntityTooLarge
+		}
+	case errors.As(err, &validationErr):
+		detail.Class, detail.Steps, status = ErrorClassValidation, validationErr.StepIds, http.StatusUnprocessableEntity
+	case errors.As(err, &cycleErr):
+		detail.Class, detail.Steps, status = ErrorClassCycle, cycleErr.StepIds, http.StatusUnprocessableEntity
+	case errors.Is(err, context.DeadlineExceeded):
+		detail.Class, status = ErrorClassTimeout, http.StatusServiceUnavailable
+	default:
+		detail.Class = ErrorClassInternal
+	}
+
+	return status, ErrorResponse{Error: detail}
+}
+
+func writeError(writer http.ResponseWriter, err error) {
+	// Only the request's own context is ever cancelled, so the client has gone and there's no one
+	// to answer. Timeouts end in context.DeadlineExceeded instead.
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	var requestErr *requestError
+	if errors.As(err, &requestErr) && requestErr.allow != "" {
+		writer.Header().Set("Allow", requestErr.allow)
+	}
+
+	status, response := getErrorResponse(err)
+	writeJson(writer, status, response)
+}
+
+func writeJson(writer http.ResponseWriter, status int, body interface{}) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	json.NewEncoder(writer).Encode(body)
+}
@@ -0,0 +1,203 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/AdamBuchen/kurtosis-take-home/scheduler"
+)
+
+func TestSchedulesYamlAndJsonBodies(t *testing.T) {
+	var tests = []struct {
+		contentType string
+		body        string
+	}{
+		{"", validJobYaml},
+		{"application/yaml", validJobYaml},
+		{"text/yaml; charset=utf-8", validJobYaml},
+		{"application/json", validJobJson},
+	}
+
+	handler := NewHandler(scheduler.DefaultResourceLimits())
+	for i, testCase := range tests {
+		recorder := doRequest(handler, http.MethodPost, "/schedule", testCase.contentType, testCase.body)
+		if recorder.Code != http.StatusOK {
+			t.Errorf("test %d: expected status 200, got %d: %s", i, recorder.Code, recorder.Body.String())
+			continue
+		}
+
+		var response ScheduleResponse
+		if decodeErr := json.Unmarshal(recorder.Body.Bytes(), &response); decodeErr != nil {
+			t.Errorf("test %d: could not decode response: %s", i, decodeErr.Error())
+		} else if !areStringSlicesEqual(response.Ordering, validJobOrdering) {
+			t.Errorf("test %d: ordering did not match, got %v", i, response.Ordering)
+		}
+	}
+}
+
+func TestReturnsStructuredErrors(t *testing.T) {
+	smallLimits := scheduler.DefaultResourceLimits()
+	smallLimits.MaxInputBytes = 64
+
+	var tests = []struct {
+		limits         scheduler.ResourceLimits
+		method         string
+		path           string
+		contentType    string
+		body           string
+		expectedStatus int
+		expectedClass  string
+		expectedSteps  []string
+	}{
+		{scheduler.DefaultResourceLimits(), http.MethodGet, "/schedule", "", validJobYaml, http.StatusMethodNotAllowed, ErrorClassRequest, nil},
+		{scheduler.DefaultResourceLimits(), http.MethodPost, "/schedule", "application/xml", validJobYaml, http.StatusUnsupportedMediaType, ErrorClassRequest, nil},
+		{scheduler.DefaultResourceLimits(), http.MethodPost, "/schedule", "", "not: [valid", http.StatusBadRequest, ErrorClassParse, nil},
+		{scheduler.DefaultResourceLimits(), http.MethodPost, "/schedule", "application/json", validJobYaml, http.StatusBadRequest, ErrorClassParse, nil},
+		{smallLimits, http.MethodPost, "/schedule", "", validJobYaml, http.StatusRequestEntityTooLarge, ErrorClassLimit, nil},
+		{scheduler.DefaultResourceLimits(), http.MethodPost, "/schedule", "", invalidDependencyJobYaml, http.StatusUnprocessableEntity, ErrorClassValidation, []string{"create user"}},
+		{scheduler.DefaultResourceLimits(), http.MethodPost, "/validate", "", cyclicJobYaml, http.StatusUnprocessableEntity, ErrorClassCycle, []string{"a", "b"}},
+		{scheduler.DefaultResourceLimits(), http.MethodPost, "/healthz", "", "", http.StatusMethodNotAllowed, ErrorClassRequest, nil},
+	}
+
+	for i, testCase := range tests {
+		recorder := doRequest(NewHandler(testCase.limits), testCase.method, testCase.path, testCase.contentType, testCase.body)
+		if recorder.Code != testCase.expectedStatus {
+			t.Errorf("test %d: expected status %d, got %d: %s", i, testCase.expectedStatus, recorder.Code, recorder.Body.String())
+			continue
+		}
+
+		var response ErrorResponse
+		if decodeErr := json.Unmarshal(recorder.Body.Bytes(), &response); decodeErr != nil {
+			t.Errorf("test %d: could not decode response: %s", i, decodeErr.Error())
+			continue
+		}
+		if response.Error.Class != testCase.expectedClass || response.Error.Message == "" {
+			t.Errorf("test %d: expected class %s, got %+v", i, testCase.expectedClass, response.Error)
+		}
+		if testCase.expectedSteps != nil && !areStringSlicesEqual(response.Error.Steps, testCase.expectedSteps) {
+			t.Errorf("test %d: expected steps %v, got %v", i, testCase.expectedSteps, response.Error.Steps)
+		}
+		if recorder.Code == http.StatusMethodNotAllowed && recorder.Header().Get("Allow") == "" {
+			t.Errorf("test %d: 405 response is missing an Allow header", i)
+		}
+	}
+}
+
+func TestBodyLimitIsInclusive(t *testing.T) {
+	limits := scheduler.DefaultResourceLimits()
+	limits.MaxInputBytes = int64(len(validJobYaml))
+
+	recorder := doRequest(NewHandler(limits), http.MethodPost, "/schedule", "", validJobYaml)
+	if recorder.Code != http.StatusOK {
+		t.Errorf("expected a body exactly at the limit to be accepted, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestClientDisconnectsAreNotServerErrors(t *testing.T) {
+	handler := NewHandler(scheduler.DefaultResourceLimits())
+
+	// The body stops partway through the upload
+	brokenRequest := httptest.NewRequest(http.MethodPost, "/schedule", iotest.ErrReader(errors.New("unexpected EOF")))
+	brokenRecorder := httptest.NewRecorder()
+	handler.ServeHTTP(brokenRecorder, brokenRequest)
+	if brokenRecorder.Code != http.StatusBadRequest || !strings.Contains(brokenRecorder.Body.String(), ErrorClassRequest) {
+		t.Errorf("expected a 400 request error, got %d: %s", brokenRecorder.Code, brokenRecorder.Body.String())
+	}
+
+	// The client has gone before scheduling starts, so no response is written at all
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	goneRequest := httptest.NewRequest(http.MethodPost, "/schedule", strings.NewReader(validJobYaml)).WithContext(ctx)
+	goneRecorder := httptest.NewRecorder()
+	handler.ServeHTTP(goneRecorder, goneRequest)
+	if goneRecorder.Body.Len() != 0 {
+		t.Errorf("expected no response, got %d: %s", goneRecorder.Code, goneRecorder.Body.String())
+	}
+}
+
+func TestValidateAndHealthz(t *testing.T) {
+	handler := NewHandler(scheduler.DefaultResourceLimits())
+
+	validateRecorder := doRequest(handler, http.MethodPost, "/validate", "application/yaml", validJobYaml)
+	var validateResponse ValidateResponse
+	json.Unmarshal(validateRecorder.Body.Bytes(), &validateResponse)
+	if validateRecorder.Code != http.StatusOK || !validateResponse.Valid || validateResponse.Steps != len(validJobOrdering) {
+		t.Errorf("unexpected validate response %d: %s", validateRecorder.Code, validateRecorder.Body.String())
+	}
+
+	healthRecorder := doRequest(handler, http.MethodGet, "/healthz", "", "")
+	if healthRecorder.Code != http.StatusOK || !strings.Contains(healthRecorder.Body.String(), `"ok"`) {
+		t.Errorf("unexpected healthz response %d: %s", healthRecorder.Code, healthRecorder.Body.String())
+	}
+}
+
+func doRequest(handler http.Handler, method string, path string, contentType string, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func areStringSlicesEqual(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+const validJobYaml string = `
+- step: "create user"
+  dependencies: ["prepare database"]
+  precedence: 100
+- step: "prepare database"
+  dependencies: []
+  precedence: 10
+- step: "warm cache"
+  dependencies: []
+  precedence: 5
+`
+
+const validJobJson string = `[
+	{"step": "create user", "dependencies": ["prepare database"], "precedence": 100},
+	{"step": "prepare database", "dependencies": [], "precedence": 10},
+	{"step": "warm cache", "precedence": 5}
+]`
+
+var validJobOrdering = []string{
+	"prepare database",
+	"create user",
+	"warm cache",
+}
+
+const invalidDependencyJobYaml string = `
+- step: "create user"
+  dependencies: ["prepare databse"]
+  precedence: 100
+- step: "prepare database"
+  dependencies: []
+  precedence: 10
+`
+
+const cyclicJobYaml string = `
+- step: "a"
+  dependencies: ["b"]
+  precedence: 1
+- step: "b"
+  dependencies: ["a"]
+  precedence: 1
+`
@@ -1,17 +1,53 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/AdamBuchen/kurtosis-take-home/scheduler"
 )
 
-func handleFatalError(errStr string) {
-	errCode := 1
-	fmt.Println("error detected: " + errStr)
-	fmt.Printf("error code: %d\n", errCode)
+// Permissions for new files other than orderings, such as fix --output and error reports
+const defaultFileMode fs.FileMode = 0644
+
+// Permissions for ordering files, set with --output-mode. Without it an existing ordering file
+// keeps its permissions.
+var outputFileMode = fileModeFlag{mode: defaultFileMode}
+
+// Set with --remove-output-on-error. Normally a failed run leaves its ordering file untouched,
+// still holding whatever an earlier run wrote; with this set the file is removed instead, so
+// nothing stale can be mistaken for this run's result.
+var removeOutputOnError bool
+
+// The ordering file the current run writes, which exitWithError removes if it's asked to
+var runOutputPath string
+
+// A flag holding Unix permissions written in octal, like chmod takes them
+type fileModeFlag struct {
+	mode  fs.FileMode
+	isSet bool // Whether the flag was given, rather than left at its default
+}
+
+func (modeFlag *fileModeFlag) String() string {
+	return fmt.Sprintf("%04o", uint32(modeFlag.mode))
+}
 
-	os.Exit(errCode)
+func (modeFlag *fileModeFlag) Set(value string) error {
+	mode, parseErr := strconv.ParseUint(value, 8, 32)
+	if parseErr != nil || mode > 0777 {
+		return fmt.Errorf("must be octal permissions such as 0644")
+	}
+	modeFlag.mode = fs.FileMode(mode)
+	modeFlag.isSet = true
+	return nil
 }
 
 func getStringFromPath(inputPath string) (string, error) {
@@ -22,18 +58,175 @@ func getStringFromPath(inputPath string) (string, error) {
 	}
 	defer file.Close()
 
-	bytes, err := io.ReadAll(file)
+	// Read one byte past the cap so we can tell a file that's exactly at the limit from one over it
+	var reader io.Reader = file
+	if resourceLimits.MaxInputBytes > 0 {
+		reader = io.LimitReader(file, resourceLimits.MaxInputBytes+1)
+	}
+
+	bytes, err := io.ReadAll(reader)
 	if err != nil {
 		return "", err
 	}
 
+	if resourceLimits.MaxInputBytes > 0 && int64(len(bytes)) > resourceLimits.MaxInputBytes {
+		return "", &scheduler.LimitError{
+			Limit:   "max-input-bytes",
+			Message: fmt.Sprintf("%s is larger than the limit of %d bytes", inputPath, resourceLimits.MaxInputBytes),
+		}
+	}
+
 	return string(bytes), nil
 }
 
+// Writes the file atomically, keeping the permissions of the file it replaces. New files are
+// created with defaultFileMode.
 func writeStringToFile(contents string, outputPath string) error {
-	err := os.WriteFile(outputPath, []byte(contents), 0666)
-	if err != nil {
-		return err
+
+	perm := defaultFileMode
+	if info, statErr := os.Stat(outputPath); statErr == nil {
+		perm = info.Mode().Perm()
 	}
+
+	return writeFileAtomically(outputPath, []byte(contents), perm)
+}
+
+// Moves the finished temp file into place. Tests replace it to simulate filesystems that refuse.
+var renameFile = os.Rename
+
+// Writes to a temp file in the same directory, fsyncs it and renames it over outputPath, so that
+// readers see either the old contents or the new, never a partial write. If anything fails the
+// temp file is removed and outputPath is left as it was. A symlink is written through, replacing
+// its target rather than the link itself. A file that can't be renamed over, such as one bind
+// mounted into a container, is overwritten in place instead, which isn't atomic.
+func writeFileAtomically(outputPath string, contents []byte, perm fs.FileMode) error {
+
+	if resolvedPath, resolveErr := filepath.EvalSymlinks(outputPath); resolveErr == nil {
+		outputPath = resolvedPath
+	}
+
+	dir, base := filepath.Split(outputPath)
+	if dir == "" {
+		dir = "."
+	}
+
+	tempFile, createErr := os.CreateTemp(dir, "."+base+".tmp-*")
+	if createErr != nil {
+		return fmt.Errorf("could not create a temp file next to %s: %w", outputPath, createErr)
+	}
+	tempPath := tempFile.Name()
+
+	writeErr := func() error {
+		if _, err := tempFile.Write(contents); err != nil {
+			return err
+		}
+		if err := tempFile.Chmod(perm); err != nil {
+			return err
+		}
+		if err := tempFile.Sync(); err != nil {
+			return err
+		}
+		return tempFile.Close()
+	}()
+	if writeErr != nil {
+		tempFile.Close()
+		os.Remove(tempPath)
+		return writeErr
+	}
+
+	if renameErr := renameFile(tempPath, outputPath); renameErr != nil {
+		os.Remove(tempPath)
+		if errors.Is(renameErr, syscall.EBUSY) || errors.Is(renameErr, syscall.EXDEV) {
+			return writeFileInPlace(outputPath, contents, perm)
+		}
+		return renameErr
+	}
+
+	// Syncing the directory makes the rename itself durable. Not every platform can open a
+	// directory for syncing, and the file's contents are already safe, so failures are ignored.
+	if dirFile, openErr := os.Open(dir); openErr == nil {
+		dirFile.Sync()
+		dirFile.Close()
+	}
+
 	return nil
 }
+
+// Truncates and rewrites an existing file, for when writeFileAtomically can't replace it
+func writeFileInPlace(outputPath string, contents []byte, perm fs.FileMode) error {
+
+	file, openErr := os.OpenFile(outputPath, os.O_WRONLY|os.O_TRUNC, perm)
+	if openErr != nil {
+		return openErr
+	}
+
+	writeErr := func() error {
+		if _, err := file.Write(contents); err != nil {
+			return err
+		}
+		if err := file.Chmod(perm); err != nil {
+			return err
+		}
+		return file.Sync()
+	}()
+	if writeErr != nil {
+		file.Close()
+		return writeErr
+	}
+
+	return file.Close()
+}
+
+// Removes a failed job's ordering file if --remove-output-on-error is set
+func removeOutputAfterError(outputPath string) {
+
+	if !removeOutputOnError || outputPath == "" {
+		return
+	}
+
+	removeErr := os.Remove(outputPath)
+	switch {
+	case errors.Is(removeErr, fs.ErrNotExist):
+	case removeErr != nil:
+		slog.Warn("could not remove output after error", "output", outputPath, "error", removeErr.Error())
+	default:
+		slog.Info("removed output after error", "output", outputPath)
+	}
+}
+
+// Writes an ordering one step ID per line. Per instructions, an output ordering is always
+// terminated by a newline. --output-mode, if given, replaces the file's permissions.
+func writeOrderingToFile(outputLines []string, outputPath string) error {
+
+	var builder strings.Builder
+	for _, line := range outputLines {
+		builder.WriteString(line + "\n")
+	}
+
+	if !outputFileMode.isSet {
+		return writeStringToFile(builder.String(), outputPath)
+	}
+
+	return writeFileAtomically(outputPath, []byte(builder.String()), outputFileMode.mode)
+}
+
+// Reads a newline-delimited list of step IDs (e.g. a previous output ordering). Surrounding
+// whitespace is trimmed and blank lines are skipped.
+func getStepIdsFromPath(inputPath string) ([]string, error) {
+
+	contents, err := getStringFromPath(inputPath)
+	if err != nil {
+		return nil, err
+	}
+
+	stepIds := make([]string, 0)
+	for _, line := range strings.Split(contents, "\n") {
+		stepId := scheduler.NormalizeStepId(line)
+		if stepId == "" {
+			continue
+		}
+		stepIds = append(stepIds, stepId)
+	}
+
+	return stepIds, nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestFailedWritesLeaveNoTraces(t *testing.T) {
+	dir := t.TempDir()
+
+	// A non-empty directory can't be renamed over, so the write fails after its temp file exists
+	targetDir := filepath.Join(dir, "ordering.txt")
+	writeTestFile(t, filepath.Join(targetDir, "keep"), "kept")
+
+	var tests = []struct {
+		outputPath string
+	}{
+		{targetDir},
+		{filepath.Join(dir, "missing", "ordering.txt")},
+	}
+
+	for i, testCase := range tests {
+		if writeErr := writeOrderingToFile([]string{"build"}, testCase.outputPath); writeErr == nil {
+			t.Errorf("test %d: should have received error, did not", i)
+		}
+
+		entries, readErr := os.ReadDir(dir)
+		if readErr != nil {
+			t.Fatalf("received unexpected error: %s", readErr.Error())
+		}
+		if len(entries) != 1 || entries[0].Name() != "ordering.txt" {
+			t.Errorf("test %d: expected only the original target to remain, got %v", i, entries)
+		}
+		if contents, _ := os.ReadFile(filepath.Join(targetDir, "keep")); string(contents) != "kept" {
+			t.Errorf("test %d: the existing target was changed", i)
+		}
+	}
+}
+
+func TestWritesApplyOutputMode(t *testing.T) {
+	defer func(modeFlag fileModeFlag) { outputFileMode = modeFlag }(outputFileMode)
+
+	var tests = []struct {
+		modeFlag     string // --output-mode, or empty if it isn't given
+		existingMode fs.FileMode
+		expectedMode fs.FileMode
+	}{
+		// An existing file's permissions are replaced when --output-mode is given...
+		{"0600", defaultFileMode, 0600},
+		{"0640", 0600, 0640},
+		{"0644", 0600, 0644},
+		// ...and kept when it isn't
+		{"", 0600, 0600},
+		{"", 0640, 0640},
+	}
+
+	for i, testCase := range tests {
+		outputPath := filepath.Join(t.TempDir(), "ordering.txt")
+		writeTestFile(t, outputPath, "old\n")
+		if chmodErr := os.Chmod(outputPath, testCase.existingMode); chmodErr != nil {
+			t.Fatalf("test %d: received unexpected error: %s", i, chmodErr.Error())
+		}
+
+		outputFileMode = fileModeFlag{mode: defaultFileMode}
+		if testCase.modeFlag != "" {
+			if setErr := outputFileMode.Set(testCase.modeFlag); setErr != nil {
+				t.Fatalf("test %d: received unexpected error: %s", i, setErr.Error())
+			}
+		}
+		if writeErr := writeOrderingToFile([]string{"build", "deploy"}, outputPath); writeErr != nil {
+			t.Fatalf("test %d: received unexpected error: %s", i, writeErr.Error())
+		}
+
+		info, statErr := os.Stat(outputPath)
+		if statErr != nil {
+			t.Fatalf("test %d: received unexpected error: %s", i, statErr.Error())
+		}
+		if info.Mode().Perm() != testCase.expectedMode {
+			t.Errorf("test %d: expected mode %04o, got %04o", i, testCase.expectedMode, info.Mode().Perm())
+		}
+		if contents, _ := os.ReadFile(outputPath); string(contents) != "build\ndeploy\n" {
+			t.Errorf("test %d: unexpected contents %q", i, string(contents))
+		}
+	}
+}
+
+func TestWritesGoThroughSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	targetPath := filepath.Join(dir, "orderings", "ordering.txt")
+	linkPath := filepath.Join(dir, "latest.txt")
+	writeTestFile(t, targetPath, "old\n")
+	if linkErr := os.Symlink(targetPath, linkPath); linkErr != nil {
+		t.Skipf("symlinks aren't available: %s", linkErr.Error())
+	}
+
+	if writeErr := writeOrderingToFile([]string{"build"}, linkPath); writeErr != nil {
+		t.Fatalf("received unexpected error: %s", writeErr.Error())
+	}
+
+	if info, lstatErr := os.Lstat(linkPath); lstatErr != nil || info.Mode()&fs.ModeSymlink == 0 {
+		t.Errorf("expected %s to still be a symlink", linkPath)
+	}
+	if contents, _ := os.ReadFile(targetPath); string(contents) != "build\n" {
+		t.Errorf("expected the target to be rewritten, got %q", string(contents))
+	}
+}
+
+// A file bind mounted into a container can't be renamed over (EBUSY), nor can one on another
+// device (EXDEV), so those are rewritten in place. Any other rename failure is still an error.
+func TestWritesInPlaceWhenRenameIsRefused(t *testing.T) {
+	defer func(rename func(string, string) error) { renameFile = rename }(renameFile)
+
+	// The old contents are longer than the new, so a missed truncate would leave some behind
+	const oldContents = "prepare database\ncreate user\n"
+
+	var tests = []struct {
+		renameErr        error
+		expectedOk       bool
+		expectedContents string
+	}{
+		{syscall.EBUSY, true, "build\n"},
+		{syscall.EXDEV, true, "build\n"},
+		{syscall.EACCES, false, oldContents},
+	}
+
+	for i, testCase := range tests {
+		dir := t.TempDir()
+		outputPath := filepath.Join(dir, "ordering.txt")
+		writeTestFile(t, outputPath, oldContents)
+
+		renameFile = func(oldPath string, newPath string) error {
+			return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: testCase.renameErr}
+		}
+		writeErr := writeOrderingToFile([]string{"build"}, outputPath)
+		if (writeErr == nil) != testCase.expectedOk {
+			t.Errorf("test %d: expected success %v, got %v", i, testCase.expectedOk, writeErr)
+		}
+
+		if contents, _ := os.ReadFile(outputPath); string(contents) != testCase.expectedContents {
+			t.Errorf("test %d: expected contents %q, got %q", i, testCase.expectedContents, string(contents))
+		}
+		// The temp file is cleaned up either way
+		if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+			t.Errorf("test %d: expected only the output file to remain, got %v", i, entries)
+		}
+	}
+}
+
+func TestRemovesOutputAfterErrorOnlyWhenAsked(t *testing.T) {
+	defer func(remove bool) { removeOutputOnError = remove }(removeOutputOnError)
+
+	var tests = []struct {
+		removeOutputOnError bool
+		expectedExists      bool
+	}{
+		{false, true},
+		{true, false},
+	}
+
+	for i, testCase := range tests {
+		outputPath := filepath.Join(t.TempDir(), "ordering.txt")
+		writeTestFile(t, outputPath, "build\n")
+
+		removeOutputOnError = testCase.removeOutputOnError
+		removeOutputAfterError(outputPath)
+		// A second call finds nothing to remove, which is fine
+		removeOutputAfterError(outputPath)
+
+		_, statErr := os.Stat(outputPath)
+		if exists := statErr == nil; exists != testCase.expectedExists {
+			t.Errorf("test %d: expected the output to exist: %v, got %v", i, testCase.expectedExists, exists)
+		}
+	}
+}
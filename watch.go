@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/AdamBuchen/kurtosis-take-home/scheduler"
+)
+
+// Reschedules the job at inputPath every time it changes, until ctx is cancelled. The file is
+// polled rather than watched with inotify, which also works on network and container mounts and
+// survives editors that save by replacing the file. Each valid version is written to outputPath
+// and printed as a diff against the last valid version; an invalid one prints its errors and
+// leaves the output file as it was.
+func watchUserJob(ctx context.Context, inputPath string, outputPath string, interval time.Duration) {
+
+	watcher := &jobWatcher{inputPath: inputPath, outputPath: outputPath, stdout: os.Stdout, stderr: os.Stderr}
+
+	fmt.Printf("watching %s every %s, press Ctrl-C to stop\n", inputPath, interval)
+	watcher.poll()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			watcher.poll()
+		}
+	}
+}
+
+// What the watcher last saw, so it can tell real changes from a save with the same contents
+type jobWatcher struct {
+	inputPath  string
+	outputPath string
+	stdout     io.Writer // Where orderings and diffs are printed
+	stderr     io.Writer // Where errors are printed
+
+	modTime time.Time
+	size    int64
+	yamlStr string
+	readErr string // The last read error printed, so a missing file isn't reported every poll
+
+	validYamlStr string // The last version that scheduled, which the next valid one is diffed against
+	hasValid     bool
+}
+
+// Checks the input file once and reschedules it if its contents have changed
+func (watcher *jobWatcher) poll() {
+
+	info, statErr := os.Stat(watcher.inputPath)
+	if statErr != nil {
+		watcher.reportReadError(statErr)
+		return
+	}
+	if watcher.readErr == "" && info.ModTime().Equal(watcher.modTime) && info.Size() == watcher.size {
+		return
+	}
+
+	yamlStr, fileReadErr := getStringFromPath(watcher.inputPath)
+	if fileReadErr != nil {
+		watcher.reportReadError(fileReadErr)
+		return
+	}
+
+	isFirstRead := watcher.modTime.IsZero()
+	watcher.modTime = info.ModTime()
+	watcher.size = info.Size()
+	watcher.readErr = ""
+
+	if !isFirstRead && yamlStr == watcher.yamlStr {
+		return
+	}
+	watcher.yamlStr = yamlStr
+
+	if !isFirstRead {
+		fmt.Fprintf(watcher.stdout, "\n[%s] %s changed\n", time.Now().Format("15:04:05"), watcher.inputPath)
+	}
+	watcher.reschedule(yamlStr)
+}
+
+// Parses, validates and schedules the new version, printing the diff or the errors
+func (watcher *jobWatcher) reschedule(yamlStr string) {
+
+	outputLines, processingErr := scheduler.ProcessUserJob(yamlStr, getSchedulerOptions()...)
+	if processingErr != nil {
+		fmt.Fprintln(watcher.stderr, "errors:")
+		for _, line := range strings.Split(strings.TrimRight(processingErr.Error(), "\n"), "\n") {
+			fmt.Fprintln(watcher.stderr, "  "+line)
+		}
+		if removeOutputOnError {
+			removeOutputAfterError(watcher.outputPath)
+		} else if watcher.hasValid {
+			fmt.Fprintln(watcher.stderr, watcher.outputPath+" still holds the last valid ordering")
+		}
+		return
+	}
+
+	switch {
+	case !watcher.hasValid:
+		fmt.Fprintln(watcher.stdout, "ordering:")
+		for i, stepId := range outputLines {
+			fmt.Fprintf(watcher.stdout, "  %4d  %s\n", i+1, stepId)
+		}
+	case yamlStr == watcher.validYamlStr:
+		fmt.Fprintln(watcher.stdout, "job is valid again, ordering unchanged")
+	default:
+		jobDiff, diffErr := scheduler.DiffUserJobs(watcher.validYamlStr, yamlStr, getSchedulerOptions()...)
+		if diffErr != nil {
+			fmt.Fprintln(watcher.stderr, "could not diff against the last valid version: "+diffErr.Error())
+		} else {
+			fmt.Fprint(watcher.stdout, jobDiff.String())
+		}
+	}
+
+	watcher.validYamlStr = yamlStr
+	watcher.hasValid = true
+
+	if saveErr := writeOrderingToFile(outputLines, watcher.outputPath); saveErr != nil {
+		fmt.Fprintln(watcher.stderr, "could not write out file: "+saveErr.Error())
+		return
+	}
+	fmt.Fprintf(watcher.stdout, "wrote %d step(s) to %s\n", len(outputLines), watcher.outputPath)
+}
+
+// Prints a read error unless it's the one already printed, and forgets the file's last state so
+// that it's reread once it's back
+func (watcher *jobWatcher) reportReadError(readErr error) {
+
+	message := "could not open input path: " + readErr.Error()
+	if message != watcher.readErr {
+		fmt.Fprintln(watcher.stderr, message)
+	}
+	watcher.readErr = message
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatcherOnlyReschedulesRealChanges(t *testing.T) {
+	dir := t.TempDir()
+	inputPath := filepath.Join(dir, "job.yml")
+	outputPath := filepath.Join(dir, "ordering.txt")
+
+	var stdout, stderr bytes.Buffer
+	watcher := &jobWatcher{inputPath: inputPath, outputPath: outputPath, stdout: &stdout, stderr: &stderr}
+
+	// Each step changes the input file, polls once, and checks what was printed. An empty
+	// expectation means nothing should have been printed at all.
+	var tests = []struct {
+		action         string // write, remove or none
+		contents       string
+		expectedStdout string
+		expectedStderr string
+		expectedOutput string
+	}{
+		{"write", validBatchJobYaml, "ordering:", "", "build\ndeploy\n"},
+		{"write", validBatchJobYaml, "", "", "build\ndeploy\n"}, // Saved again with the same contents
+		{"write", cyclicBatchJobYaml, "job.yml changed", "still holds the last valid ordering", "build\ndeploy\n"},
+		{"remove", "", "", "could not open input path", "build\ndeploy\n"},
+		{"none", "", "", "", "build\ndeploy\n"}, // Still missing, and already reported
+		{"write", validBatchJobYaml, "job is valid again", "", "build\ndeploy\n"},
+	}
+
+	modTime := time.Now().Add(-time.Hour)
+	for i, testCase := range tests {
+		stdout.Reset()
+		stderr.Reset()
+
+		switch testCase.action {
+		case "write":
+			writeTestFile(t, inputPath, testCase.contents)
+			// Give every save its own mtime, however coarse the filesystem's timestamps are
+			modTime = modTime.Add(time.Second)
+			if chtimesErr := os.Chtimes(inputPath, modTime, modTime); chtimesErr != nil {
+				t.Fatalf("test %d: received unexpected error: %s", i, chtimesErr.Error())
+			}
+		case "remove":
+			if removeErr := os.Remove(inputPath); removeErr != nil {
+				t.Fatalf("test %d: received unexpected error: %s", i, removeErr.Error())
+			}
+		}
+
+		watcher.poll()
+
+		if !isExpectedWatcherOutput(stdout.String(), testCase.expectedStdout) {
+			t.Errorf("test %d: expected stdout to contain %q, got %q", i, testCase.expectedStdout, stdout.String())
+		}
+		if !isExpectedWatcherOutput(stderr.String(), testCase.expectedStderr) {
+			t.Errorf("test %d: expected stderr to contain %q, got %q", i, testCase.expectedStderr, stderr.String())
+		}
+		if contents, _ := os.ReadFile(outputPath); string(contents) != testCase.expectedOutput {
+			t.Errorf("test %d: expected output file %q, got %q", i, testCase.expectedOutput, string(contents))
+		}
+	}
+}
+
+func isExpectedWatcherOutput(output string, expected string) bool {
+	if expected == "" {
+		return output == ""
+	}
+	return strings.Contains(output, expected)
+}